@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,6 +18,9 @@ import (
 	"github.com/dmitrycr/ShortUrl/internal/handler"
 	"github.com/dmitrycr/ShortUrl/internal/service"
 	"github.com/dmitrycr/ShortUrl/internal/storage"
+	"github.com/dmitrycr/ShortUrl/internal/validator"
+	"github.com/dmitrycr/ShortUrl/pkg/blocklist"
+	"github.com/dmitrycr/ShortUrl/pkg/threatlist"
 )
 
 func main() {
@@ -33,6 +37,16 @@ func main() {
 
 	// Настраиваем логгер
 	logger := setupLogger(cfg)
+
+	// Подкоманда migrate применяет миграции и завершает работу
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), cfg, logger, os.Args[2:]); err != nil {
+			logger.Error("migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	logger.Info("starting url shortener service",
 		"environment", cfg.Environment,
 		"port", cfg.ServerPort,
@@ -40,26 +54,160 @@ func main() {
 
 	// Подключаемся к базе данных
 	ctx := context.Background()
-	store, err := storage.NewPostgresStorage(ctx, cfg.DatabaseURL)
+	store, err := storage.Open(ctx, cfg.DatabaseURL)
 	if err != nil {
 		logger.Error("failed to connect to database", "error", err)
 		os.Exit(1)
 	}
 	defer store.Close()
-	logger.Info("connected to database")
+
+	// Без AUTO_MIGRATE схема применяется отдельным шагом: server migrate up
+	if cfg.AutoMigrate {
+		if err := autoMigrate(ctx, cfg, store, logger); err != nil {
+			logger.Error("migration failed", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Коды без учета регистра: уникальный индекс по lower(short_code) создает
+	// миграция case_insensitive_codes (server migrate up или AUTO_MIGRATE)
+	if cfg.CodeCaseInsensitive {
+		folder, ok := store.(storage.CaseInsensitiveCodes)
+		if !ok {
+			logger.Error("storage does not support case-insensitive codes", "scheme", storage.Scheme(cfg.DatabaseURL))
+			os.Exit(1)
+		}
+		if err := folder.EnableCaseInsensitiveCodes(ctx); err != nil {
+			logger.Error("failed to enable case-insensitive codes",
+				"error", err,
+				"hint", "run `server migrate up`; `server migrate case-conflicts` lists conflicting codes",
+			)
+			os.Exit(1)
+		}
+	}
+	logger.Info("connected to database", "scheme", storage.Scheme(cfg.DatabaseURL))
+
+	// Загружаем список запрещенных слов
+	var blocked *blocklist.Blocklist
+	if cfg.BlocklistFile != "" {
+		blocked, err = blocklist.Load(cfg.BlocklistFile)
+		if err != nil {
+			logger.Error("failed to load blocklist", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("blocklist loaded", "words", blocked.Len())
+	}
+
+	// Зарезервированные коды; пути роутера добавятся после его создания
+	reserved := validator.NewReservedWords(cfg.ReservedCodes...)
+
+	// DNS-проверка целевых хостов: имя не должно вести во внутреннюю сеть
+	var resolver validator.Resolver
+	if cfg.ResolveDestinations {
+		resolver = net.DefaultResolver
+	}
+
+	// Раскрытие ссылок других сокращателей: по одному запросу на шаг цепочки
+	var shortenerClient *http.Client
+	if cfg.ResolveShorteners {
+		shortenerClient = &http.Client{Timeout: 5 * time.Second, Transport: validator.NewSafeTransport()}
+	}
+
+	// Файлы правил перечитываются при изменении и по SIGHUP до остановки сервера
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+
+	// Политика доменов целевых URL
+	var domains validator.DomainChecker
+	if cfg.DomainPolicyFile != "" {
+		policy, err := validator.OpenDomainPolicy(cfg.DomainPolicyFile)
+		if err != nil {
+			logger.Error("failed to load domain policy", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("domain policy loaded", "rules", policy.Len())
+
+		go watchReload(watchCtx, "domain policy", policy, cfg.DomainPolicyReloadInterval, logger)
+		domains = policy
+	}
+
+	// Списки вредоносных URL проверяются при создании ссылки и при каждом переходе
+	var threats service.ThreatChecker
+	if len(cfg.ThreatFeeds) > 0 {
+		feed, err := threatlist.Open(cfg.ThreatFeeds...)
+		if err != nil {
+			logger.Error("failed to load threat feeds", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("threat feeds loaded", "files", len(cfg.ThreatFeeds), "entries", feed.Len())
+
+		go watchReload(watchCtx, "threat feeds", feed, cfg.ThreatFeedsReloadInterval, logger)
+		threats = feed
+	}
 
 	// Создаем сервис
-	urlService := service.NewURLService(service.Config{
-		Storage:    store,
-		BaseURL:    cfg.BaseURL,
-		CodeLength: cfg.CodeLength,
+	urlService, err := service.NewURLService(service.Config{
+		Storage:      store,
+		BaseURL:      cfg.BaseURL,
+		CodeLength:   cfg.CodeLength,
+		CodeAlphabet: cfg.CodeAlphabet,
+		CodeStrategy: cfg.CodeStrategy,
+		CodeSecret:   []byte(cfg.CodeSecret),
+		Logger:       logger,
+		Blocklist:    blocked,
+
+		CodeChecksum:       cfg.CodeChecksum,
+		CodeChecksumLegacy: cfg.CodeChecksumLegacy,
+
+		CodeCaseInsensitive: cfg.CodeCaseInsensitive,
+
+		CodeWords:         cfg.CodeWords,
+		CodeWordSeparator: cfg.CodeWordSeparator,
+
+		ReservedWords: reserved,
+
+		AllowedSchemes: cfg.AllowedSchemes,
+		Resolver:       resolver,
+		DomainPolicy:   domains,
+		ThreatList:     threats,
+
+		HealthCheckInterval:    cfg.HealthCheckInterval,
+		HealthCheckConcurrency: cfg.HealthCheckConcurrency,
+		HealthCheckHostDelay:   cfg.HealthCheckHostDelay,
+
+		ServiceDomains:  cfg.ServiceDomains,
+		ShortenerHosts:  cfg.ShortenerHosts,
+		ShortenerClient: shortenerClient,
+
+		TrackingParams:  cfg.TrackingParams,
+		SortQueryParams: cfg.SortQueryParams,
+
+		CodeGrowthThreshold: cfg.CodeGrowthThreshold,
+
+		CodePoolSize:           cfg.CodePoolSize,
+		CodePoolLowWatermark:   cfg.CodePoolLowWatermark,
+		CodePoolReservationTTL: cfg.CodePoolReservationTTL,
 	})
+	if err != nil {
+		logger.Error("failed to create service", "error", err)
+		os.Exit(1)
+	}
 
 	// Создаем handlers
-	h := handler.New(urlService, logger)
+	h := handler.New(urlService, logger, handler.WithTrustedProxies(cfg.TrustedProxies))
 
 	// Создаем роутер
 	router := handler.NewRouter(h)
+	reserved.Add(handler.RouteWords(router)...)
+
+	// Существующие ссылки с зарезервированными кодами недоступны — сообщаем о них
+	conflicts, err := urlService.ReservedConflicts(ctx)
+	if err != nil {
+		logger.Error("failed to check reserved codes", "error", err)
+	}
+	for _, code := range conflicts {
+		logger.Warn("existing short link is shadowed by a reserved name", "code", code)
+	}
 
 	// Создаем HTTP сервер
 	srv := &http.Server{
@@ -95,9 +243,60 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Возвращаем невыданные коды пула до закрытия хранилища
+	if err := urlService.Close(ctx); err != nil {
+		logger.Error("failed to stop service", "error", err)
+	}
+
 	logger.Info("server stopped gracefully")
 }
 
+// reloadable правила из файлов, которые перечитываются без перезапуска
+type reloadable interface {
+	Reload() error
+	ReloadIfChanged() (bool, error)
+	Len() int
+}
+
+// watchReload перечитывает правила при изменении файлов и по SIGHUP
+// Ошибочный файл не применяется — продолжают действовать прежние правила
+func watchReload(ctx context.Context, name string, rules reloadable, interval time.Duration, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		var (
+			reloaded bool
+			err      error
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			reloaded, err = true, rules.Reload()
+		case <-tick:
+			reloaded, err = rules.ReloadIfChanged()
+		}
+
+		if err != nil {
+			logger.Error("failed to reload "+name+", keeping previous rules", "error", err)
+			continue
+		}
+		if reloaded {
+			logger.Info(name+" reloaded", "entries", rules.Len())
+		}
+	}
+}
+
 // setupLogger настраивает логгер в зависимости от окружения
 func setupLogger(cfg *config.Config) *slog.Logger {
 	var handler slog.Handler
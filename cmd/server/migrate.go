@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/config"
+	"github.com/dmitrycr/ShortUrl/internal/migrate"
+	"github.com/dmitrycr/ShortUrl/internal/storage"
+)
+
+const migrateUsage = "usage: server migrate [up | down [N] | status | case-conflicts | revert-disabled]"
+
+// runMigrate выполняет подкоманду migrate: up, down [N], status, case-conflicts, revert-disabled
+func runMigrate(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	steps := 1
+	if command == "down" && len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return errors.New(migrateUsage)
+		}
+		steps = n
+	}
+
+	store, err := storage.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer store.Close()
+
+	if command == "case-conflicts" {
+		return printCaseConflicts(ctx, store)
+	}
+
+	migratable, ok := store.(storage.Migratable)
+	if !ok {
+		return fmt.Errorf("storage %q has no schema to migrate", storage.Scheme(cfg.DatabaseURL))
+	}
+
+	migrator, err := migratable.Migrator(migrationOptions(cfg)...)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		return migrateUp(ctx, cfg, migrator, logger)
+
+	case "revert-disabled":
+		// Откат удаляет схему функции (например, уникальный индекс по lower(short_code)),
+		// поэтому выполняется только явной командой и никогда внутри up
+		reverted, err := migrator.RevertDisabled(ctx)
+		for _, m := range reverted {
+			logger.Info("migration reverted", "version", m.Version, "name", m.Name, "feature", m.Feature)
+		}
+		if err != nil {
+			return err
+		}
+		logger.Info("disabled feature migrations reverted", "reverted", len(reverted))
+
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			logger.Info("migration reverted", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tFEATURE\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			feature := s.Feature
+			if feature == "" {
+				feature = "-"
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, feature, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// migrationFeatures состояние функций со своими миграциями по конфигурации
+func migrationFeatures(cfg *config.Config) map[string]bool {
+	return map[string]bool{
+		storage.FeatureCaseInsensitiveCodes: cfg.CodeCaseInsensitive,
+	}
+}
+
+// migrationOptions включает миграции функций по конфигурации
+func migrationOptions(cfg *config.Config) []migrate.Option {
+	var opts []migrate.Option
+	for name, enabled := range migrationFeatures(cfg) {
+		opts = append(opts, migrate.WithFeature(name, enabled))
+	}
+	return opts
+}
+
+// migrateUp применяет все ожидающие миграции; миграции выключенных функций
+// не откатывает — только предупреждает (откат: server migrate revert-disabled)
+// Экземпляры, стартующие одновременно, ждут друг друга на блокировке мигратора
+func migrateUp(ctx context.Context, cfg *config.Config, migrator *migrate.Migrator, logger *slog.Logger) error {
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		logger.Info("migration applied", "version", m.Version, "name", m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	features := migrationFeatures(cfg)
+	for _, s := range statuses {
+		if s.Feature != "" && s.AppliedAt != nil && !features[s.Feature] {
+			logger.Warn("migration of disabled feature is still applied; revert it with: server migrate revert-disabled",
+				"version", s.Version, "name", s.Name, "feature", s.Feature)
+		}
+	}
+
+	logger.Info("database is up to date", "applied", len(applied))
+	return nil
+}
+
+// autoMigrate применяет миграции при старте (AUTO_MIGRATE=true)
+// Хранилища без схемы (memory://) пропускаются
+func autoMigrate(ctx context.Context, cfg *config.Config, store storage.Storage, logger *slog.Logger) error {
+	migratable, ok := store.(storage.Migratable)
+	if !ok {
+		return nil
+	}
+
+	migrator, err := migratable.Migrator(migrationOptions(cfg)...)
+	if err != nil {
+		return err
+	}
+
+	return migrateUp(ctx, cfg, migrator, logger)
+}
+
+// printCaseConflicts выводит все коды, совпадающие без учета регистра
+// Их нужно переименовать или удалить перед включением CODE_CASE_INSENSITIVE:
+// иначе миграция case_insensitive_codes прервется с первыми из них
+func printCaseConflicts(ctx context.Context, store storage.Storage) error {
+	folder, ok := store.(storage.CaseInsensitiveCodes)
+	if !ok {
+		return fmt.Errorf("storage %T does not support case-insensitive codes", store)
+	}
+
+	conflicts, err := folder.CodeCaseConflicts(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) == 0 {
+		fmt.Println("no conflicting codes: case-insensitive mode can be enabled")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CANONICAL\tCODES")
+	for _, group := range conflicts {
+		fmt.Fprintf(w, "%s\t%s\n", strings.ToLower(group[0]), strings.Join(group, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %d groups", storage.ErrCaseConflict, len(conflicts))
+}
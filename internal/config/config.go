@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config содержит конфигурацию приложения
@@ -12,11 +15,82 @@ type Config struct {
 	ServerPort string
 	BaseURL    string
 
+	// Адреса и подсети обратных прокси: от них адрес клиента берется
+	// из X-Forwarded-For (пусто = только адрес соединения)
+	TrustedProxies []netip.Prefix
+
 	// Database
+	// Схема определяет хранилище: postgres://, file:// (SQLite), memory://
 	DatabaseURL string
 
+	// Применять миграции при старте сервера (иначе — server migrate up)
+	AutoMigrate bool
+
 	// URL Shortener
-	CodeLength int
+	CodeLength   int
+	CodeAlphabet string // base62, lowercase, crockford, unambiguous, custom:<символы>
+	CodeStrategy string // random, sequence, words
+	CodeSecret   string // ключ перестановки для sequence
+
+	// Разрешенные схемы целевых URL
+	AllowedSchemes []string
+
+	// Проверять через DNS, что хост целевого URL не ведет во внутреннюю сеть
+	ResolveDestinations bool
+
+	// Файл политики доменов целевых URL (пусто = любые домены) и период проверки изменений
+	DomainPolicyFile           string
+	DomainPolicyReloadInterval time.Duration
+
+	// Файлы списков вредоносных URL (пусто = без проверки) и период проверки изменений
+	ThreatFeeds               []string
+	ThreatFeedsReloadInterval time.Duration
+
+	// Домены сервиса помимо BASE_URL: ссылки на них отклоняются
+	ServiceDomains []string
+
+	// Хосты других сокращателей и раскрытие их ссылок до конечного адреса
+	ShortenerHosts    []string
+	ResolveShorteners bool
+
+	// Фоновая проверка целевых URL (0 = выключена): период, параллельность,
+	// минимальный промежуток между запросами к одному хосту
+	HealthCheckInterval    time.Duration
+	HealthCheckConcurrency int
+	HealthCheckHostDelay   time.Duration
+
+	// Параметры query, удаляемые из целевых URL (utm_*, fbclid...); пустой список — ничего не удалять
+	TrackingParams []string
+
+	// Сортировать параметры query целевых URL по имени
+	SortQueryParams bool
+
+	// Контрольный символ в конце сгенерированных кодов
+	CodeChecksum bool
+
+	// В базе есть коды без контрольного символа: опечатки ищутся в хранилище
+	CodeChecksumLegacy bool
+
+	// Коды без учета регистра: генерация и хранение в нижнем регистре
+	CodeCaseInsensitive bool
+
+	// Словесные коды (CODE_STRATEGY=words): количество слов и разделитель
+	CodeWords         int
+	CodeWordSeparator string
+
+	// Доля коллизий, при которой длина кодов растет (0 = фиксированная длина)
+	CodeGrowthThreshold float64
+
+	// Файл со словами, запрещенными в кодах (пусто = без фильтра)
+	BlocklistFile string
+
+	// Коды, зарезервированные помимо путей роутера
+	ReservedCodes []string
+
+	// Пул заранее зарезервированных кодов (0 = выключен)
+	CodePoolSize           int
+	CodePoolLowWatermark   int
+	CodePoolReservationTTL time.Duration
 
 	// Environment
 	Environment string // dev, staging, production
@@ -28,13 +102,70 @@ func Load() (*Config, error) {
 		ServerPort:  getEnv("SERVER_PORT", "8080"),
 		BaseURL:     getEnv("BASE_URL", "http://localhost:8080"),
 		DatabaseURL: getEnv("DATABASE_URL", ""),
+		AutoMigrate: getEnvAsBool("AUTO_MIGRATE", false),
 		CodeLength:  getEnvAsInt("CODE_LENGTH", 6),
 		Environment: getEnv("ENVIRONMENT", "dev"),
+
+		CodeAlphabet: getEnv("CODE_ALPHABET", "base62"),
+		CodeStrategy: getEnv("CODE_STRATEGY", "random"),
+		CodeSecret:   getEnv("CODE_SECRET", ""),
+
+		AllowedSchemes:      getEnvAsList("ALLOWED_SCHEMES", "http,https"),
+		ResolveDestinations: getEnvAsBool("RESOLVE_DESTINATIONS", true),
+
+		DomainPolicyFile:           getEnv("DOMAIN_POLICY_FILE", ""),
+		DomainPolicyReloadInterval: getEnvAsDuration("DOMAIN_POLICY_RELOAD_INTERVAL", 30*time.Second),
+
+		ThreatFeeds:               getEnvAsList("THREAT_FEEDS", ""),
+		ThreatFeedsReloadInterval: getEnvAsDuration("THREAT_FEEDS_RELOAD_INTERVAL", time.Minute),
+
+		ServiceDomains:    getEnvAsList("SERVICE_DOMAINS", ""),
+		ShortenerHosts:    getEnvAsList("SHORTENER_HOSTS", ""),
+		ResolveShorteners: getEnvAsBool("RESOLVE_SHORTENERS", false),
+
+		HealthCheckInterval:    getEnvAsDuration("HEALTH_CHECK_INTERVAL", 0),
+		HealthCheckConcurrency: getEnvAsInt("HEALTH_CHECK_CONCURRENCY", 4),
+		HealthCheckHostDelay:   getEnvAsDuration("HEALTH_CHECK_HOST_DELAY", 2*time.Second),
+
+		TrackingParams:  getTrackingParams(),
+		SortQueryParams: getEnvAsBool("SORT_QUERY_PARAMS", false),
+
+		CodeChecksum:       getEnvAsBool("CODE_CHECKSUM", false),
+		CodeChecksumLegacy: getEnvAsBool("CODE_CHECKSUM_LEGACY", false),
+
+		CodeCaseInsensitive: getEnvAsBool("CODE_CASE_INSENSITIVE", false),
+
+		CodeWords:         getEnvAsInt("CODE_WORDS", 2),
+		CodeWordSeparator: getEnv("CODE_WORD_SEPARATOR", "-"),
+
+		CodeGrowthThreshold: getEnvAsFloat("CODE_GROWTH_THRESHOLD", 0.1),
+
+		BlocklistFile: getEnv("BLOCKLIST_FILE", ""),
+		ReservedCodes: getEnvAsList("RESERVED_CODES", "admin,static,assets,docs,login,logout,metrics"),
+
+		CodePoolSize:           getEnvAsInt("CODE_POOL_SIZE", 0),
+		CodePoolLowWatermark:   getEnvAsInt("CODE_POOL_LOW_WATERMARK", 0),
+		CodePoolReservationTTL: getEnvAsDuration("CODE_POOL_RESERVATION_TTL", 24*time.Hour),
 	}
 
 	// Валидация обязательных параметров
+	// Вне production без DATABASE_URL работаем в памяти — удобно для демо
 	if cfg.DatabaseURL == "" {
-		return nil, fmt.Errorf("DATABASE_URL is required")
+		if cfg.IsProduction() {
+			return nil, fmt.Errorf("DATABASE_URL is required")
+		}
+		cfg.DatabaseURL = "memory://"
+	}
+
+	proxies, err := getTrustedProxies()
+	if err != nil {
+		return nil, err
+	}
+	cfg.TrustedProxies = proxies
+
+	// Без секрета коды из последовательности можно перечислить
+	if cfg.CodeStrategy == "sequence" && cfg.CodeSecret == "" {
+		return nil, fmt.Errorf("CODE_SECRET is required for CODE_STRATEGY=sequence")
 	}
 
 	return cfg, nil
@@ -63,6 +194,90 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsFloat получает переменную окружения как float64
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsBool получает переменную окружения как bool
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsDuration получает переменную окружения как длительность (30s, 5m)
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsList получает переменную окружения как список через запятую
+func getEnvAsList(key, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getTrackingParams читает STRIP_TRACKING_PARAMS; значение none отключает удаление
+func getTrackingParams() []string {
+	params := getEnvAsList("STRIP_TRACKING_PARAMS", "utm_*,fbclid,gclid")
+	if len(params) == 1 && strings.EqualFold(params[0], "none") {
+		return []string{}
+	}
+	return params
+}
+
+// getTrustedProxies читает TRUSTED_PROXIES: адреса (10.0.0.1) и подсети (10.0.0.0/8)
+// Ошибка не игнорируется: без доверенного прокси все клиенты делили бы один адрес
+func getTrustedProxies() ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, item := range getEnvAsList("TRUSTED_PROXIES", "") {
+		if prefix, err := netip.ParsePrefix(item); err == nil {
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", item)
+		}
+		addr = addr.Unmap()
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
 // IsDevelopment проверяет, запущено ли приложение в dev режиме
 func (c *Config) IsDevelopment() bool {
 	return c.Environment == "dev"
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CodeAvailability обрабатывает GET /api/codes/{code}/availability
+// Сообщает, свободен ли кастомный код, и предлагает альтернативы
+func (h *Handler) CodeAvailability(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if code == "" {
+		h.respondError(w, http.StatusBadRequest, "short code is required")
+		return
+	}
+
+	availability, err := h.service.CheckCodeAvailability(r.Context(), code)
+	if err != nil {
+		h.logger.Error("failed to check code availability",
+			"code", code,
+			"error", err,
+		)
+		h.respondError(w, http.StatusInternalServerError, "failed to check code availability")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, availability)
+}
+
+// respondCodeConflict отвечает 409 и предлагает свободные похожие коды
+func (h *Handler) respondCodeConflict(w http.ResponseWriter, r *http.Request, code string) {
+	suggestions, err := h.service.SuggestCodes(r.Context(), code)
+	if err != nil {
+		// Без подсказок 409 все равно полезен
+		h.logger.Warn("failed to suggest codes", "code", code, "error", err)
+	}
+
+	h.respondErrorWithSuggestions(w, http.StatusConflict, "this custom code is already taken", suggestions)
+}
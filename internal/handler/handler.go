@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"net/netip"
 
 	"github.com/dmitrycr/ShortUrl/internal/service"
 )
@@ -11,6 +12,20 @@ import (
 type Handler struct {
 	service *service.URLService
 	logger  *slog.Logger
+
+	// Обратные прокси, которым доверяется X-Forwarded-For
+	trustedProxies []netip.Prefix
+}
+
+// Option настраивает Handler
+type Option func(*Handler)
+
+// WithTrustedProxies задает обратные прокси: для запросов от них адрес
+// клиента берется из X-Forwarded-For
+func WithTrustedProxies(proxies []netip.Prefix) Option {
+	return func(h *Handler) {
+		h.trustedProxies = proxies
+	}
 }
 
 type ErrorResponse struct {
@@ -18,15 +33,26 @@ type ErrorResponse struct {
 	Status int    `json:"status"`
 }
 
+// SuggestionsResponse ошибка с подсказками: свободные альтернативы занятого кода
+// или исправления опечатки
+type SuggestionsResponse struct {
+	ErrorResponse
+	Suggestions []string `json:"suggestions"`
+}
+
 type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
-func New(service *service.URLService, logger *slog.Logger) *Handler {
-	return &Handler{
+func New(service *service.URLService, logger *slog.Logger, opts ...Option) *Handler {
+	h := &Handler{
 		service: service,
 		logger:  logger,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *Handler) respondJSON(w http.ResponseWriter, status int, data any) {
@@ -49,6 +75,20 @@ func (h *Handler) respondError(w http.ResponseWriter, status int, message string
 	})
 }
 
+func (h *Handler) respondErrorWithSuggestions(w http.ResponseWriter, status int, message string, suggestions []string) {
+	if suggestions == nil {
+		suggestions = []string{}
+	}
+
+	h.respondJSON(w, status, SuggestionsResponse{
+		ErrorResponse: ErrorResponse{
+			Error:  message,
+			Status: status,
+		},
+		Suggestions: suggestions,
+	})
+}
+
 func (h *Handler) decodeJSON(r *http.Request, dst any) error {
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
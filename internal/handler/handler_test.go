@@ -0,0 +1,546 @@
+package handler
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+	"github.com/dmitrycr/ShortUrl/internal/service"
+	"github.com/dmitrycr/ShortUrl/internal/storage"
+)
+
+// newTestRouter поднимает роутер поверх хранилища в памяти с заданными ссылками
+func newTestRouter(t *testing.T, cfg service.Config, urls ...*model.URL) (http.Handler, *service.URLService) {
+	t.Helper()
+
+	store := storage.NewInMemoryStorage()
+	for _, url := range urls {
+		if url.CreatedAt.IsZero() {
+			url.CreatedAt = time.Now()
+		}
+		if err := store.Save(context.Background(), url); err != nil {
+			t.Fatalf("Save(%q) failed: %v", url.ShortCode, err)
+		}
+	}
+
+	cfg.Storage = store
+	cfg.BaseURL = "http://localhost"
+	cfg.Logger = slog.New(slog.DiscardHandler)
+
+	svc, err := service.NewURLService(cfg)
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+	t.Cleanup(func() { svc.Close(context.Background()) })
+
+	return NewRouter(New(svc, cfg.Logger)), svc
+}
+
+func serve(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRedirect_CrockfordNormalization(t *testing.T) {
+	router, _ := newTestRouter(t, service.Config{CodeAlphabet: "crockford"},
+		&model.URL{ShortCode: "AB01CD", OriginalURL: "https://example.com/generated"},
+		&model.URL{ShortCode: "hello", OriginalURL: "https://example.com/custom"},
+	)
+
+	tests := []struct {
+		path     string
+		status   int
+		location string
+	}{
+		{"/AB01CD", http.StatusFound, "https://example.com/generated"},
+		{"/ab01cd", http.StatusFound, "https://example.com/generated"},
+		{"/abOicd", http.StatusFound, "https://example.com/generated"},
+		{"/aboLcd", http.StatusFound, "https://example.com/generated"},
+		// Кастомный код ищется как введен, а не в каноническом виде HE110
+		{"/hello", http.StatusFound, "https://example.com/custom"},
+		{"/AB02CD", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := serve(router, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Location"); got != tt.location {
+				t.Errorf("Expected Location %q, got %q", tt.location, got)
+			}
+		})
+	}
+
+	// Предпросмотр тоже находит ссылку по неканоническому коду
+	rec := serve(router, httptest.NewRequest(http.MethodGet, "/abolcd+", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected preview status 200, got %d", rec.Code)
+	}
+}
+
+func TestRedirect_Checksum(t *testing.T) {
+	// mistype портит первый символ кода
+	mistype := func(code string) string {
+		if code[0] == '0' {
+			return "1" + code[1:]
+		}
+		return "0" + code[1:]
+	}
+	suggestions := func(t *testing.T, rec *httptest.ResponseRecorder) []string {
+		t.Helper()
+		var body SuggestionsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return body.Suggestions
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		// Код вида сгенерированного без контрольного символа отклоняется до хранилища,
+		// даже если такая ссылка есть; кастомные коды другого вида открываются
+		router, svc := newTestRouter(t, service.Config{CodeChecksum: true},
+			&model.URL{ShortCode: "aB3xY7", OriginalURL: "https://example.com/a"},
+			&model.URL{ShortCode: "summer-sale", OriginalURL: "https://example.com/sale"},
+		)
+
+		if rec := serve(router, httptest.NewRequest(http.MethodGet, "/aB3xY7", nil)); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for code without check character, got %d", rec.Code)
+		}
+		rec := serve(router, httptest.NewRequest(http.MethodGet, "/summer-sale", nil))
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://example.com/sale" {
+			t.Errorf("Expected custom code to redirect, got %d %q", rec.Code, rec.Header().Get("Location"))
+		}
+
+		resp, err := svc.ShortenURL(context.Background(), &model.CreateURLRequest{URL: "https://example.com/new"})
+		if err != nil {
+			t.Fatalf("ShortenURL failed: %v", err)
+		}
+
+		rec = serve(router, httptest.NewRequest(http.MethodGet, "/"+resp.ShortCode, nil))
+		if rec.Code != http.StatusFound {
+			t.Errorf("Expected generated code to redirect, got %d", rec.Code)
+		}
+
+		// Опечатка: 404 с подсказкой исправления, в том числе в предпросмотре
+		for _, path := range []string{"/" + mistype(resp.ShortCode), "/" + mistype(resp.ShortCode) + "+"} {
+			rec := serve(router, httptest.NewRequest(http.MethodGet, path, nil))
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("Expected status 404 for %q, got %d", path, rec.Code)
+			}
+			if got := suggestions(t, rec); len(got) != 1 || got[0] != resp.ShortCode {
+				t.Errorf("%s: expected suggestion %q, got %v", path, resp.ShortCode, got)
+			}
+		}
+	})
+
+	t.Run("Legacy", func(t *testing.T) {
+		// Ссылки, созданные до включения контрольного символа, и кастомные коды
+		legacy := []*model.URL{
+			{ShortCode: "aB3xY7", OriginalURL: "https://example.com/a"},
+			{ShortCode: "aB3xY8", OriginalURL: "https://example.com/b"},
+			{ShortCode: "summer2024", OriginalURL: "https://example.com/summer"},
+		}
+		router, svc := newTestRouter(t, service.Config{CodeChecksum: true, CodeChecksumLegacy: true}, legacy...)
+
+		for _, url := range legacy {
+			rec := serve(router, httptest.NewRequest(http.MethodGet, "/"+url.ShortCode, nil))
+			if rec.Code != http.StatusFound || rec.Header().Get("Location") != url.OriginalURL {
+				t.Errorf("Expected %q to redirect to %q, got %d %q",
+					url.ShortCode, url.OriginalURL, rec.Code, rec.Header().Get("Location"))
+			}
+		}
+
+		resp, err := svc.ShortenURL(context.Background(), &model.CreateURLRequest{URL: "https://example.com/new"})
+		if err != nil {
+			t.Fatalf("ShortenURL failed: %v", err)
+		}
+
+		// Промах в хранилище с неверным контрольным символом — опечатка с подсказкой
+		typo := mistype(resp.ShortCode)
+		rec := serve(router, httptest.NewRequest(http.MethodGet, "/"+typo, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404 for %q, got %d", typo, rec.Code)
+		}
+		if got := suggestions(t, rec); len(got) != 1 || got[0] != resp.ShortCode {
+			t.Errorf("Expected suggestion %q, got %v", resp.ShortCode, got)
+		}
+	})
+}
+
+// testPasswordHash хеш в формате сервиса с одной итерацией — тесту не нужна стойкость
+func testPasswordHash(t *testing.T, password string) string {
+	t.Helper()
+
+	salt := []byte("test-salt")
+	key, err := pbkdf2.Key(sha256.New, password, salt, 1, 32)
+	if err != nil {
+		t.Fatalf("pbkdf2.Key failed: %v", err)
+	}
+	return fmt.Sprintf("pbkdf2-sha256$1$%s$%s",
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func TestRedirect_Password(t *testing.T) {
+	const destination = "https://example.com/private"
+	router, _ := newTestRouter(t, service.Config{}, &model.URL{
+		ShortCode:    "secret",
+		OriginalURL:  destination,
+		RedirectType: http.StatusTemporaryRedirect,
+		PasswordHash: testPasswordHash(t, "s3cret"),
+	})
+
+	unlock := func(password, remoteAddr string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}}
+		req := httptest.NewRequest(http.MethodPost, "/secret", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = remoteAddr
+		return serve(router, req)
+	}
+
+	// Без пароля: форма для браузера, JSON для API; адрес назначения не раскрывается
+	req := httptest.NewRequest(http.MethodGet, "/secret", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := serve(router, req)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `name="password"`) {
+		t.Errorf("Expected 401 with password form, got %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), destination) || rec.Header().Get("Location") != "" {
+		t.Error("Expected challenge not to reveal destination")
+	}
+
+	rec = serve(router, httptest.NewRequest(http.MethodGet, "/secret", nil))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("Expected 401 JSON challenge, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	// Пароль в заголовке не принимается: клиент повторил бы его на адресе назначения
+	req = httptest.NewRequest(http.MethodGet, "/secret", nil)
+	req.Header.Set("X-Link-Password", "s3cret")
+	if rec := serve(router, req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected header password to be ignored, got %d", rec.Code)
+	}
+
+	// Верный пароль в теле: 303, чтобы тело не ушло на адрес назначения вопреки 307
+	rec = unlock("s3cret", "198.51.100.1:1234")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != destination {
+		t.Errorf("Expected 303 to %q, got %d %q", destination, rec.Code, rec.Header().Get("Location"))
+	}
+
+	// JSON-тело тоже принимается
+	req = httptest.NewRequest(http.MethodPost, "/secret", strings.NewReader(`{"password":"s3cret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if rec := serve(router, req); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected 303 for JSON password, got %d", rec.Code)
+	}
+
+	if rec := unlock("wrong", "203.0.113.7:1234"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for wrong password, got %d", rec.Code)
+	}
+
+	// Перебор с одного адреса упирается в блокировку
+	var locked bool
+	for range 10 {
+		if rec := unlock("wrong", "203.0.113.7:1234"); rec.Code == http.StatusTooManyRequests {
+			locked = true
+			break
+		}
+	}
+	if !locked {
+		t.Fatal("Expected 429 after repeated wrong passwords")
+	}
+	if rec := unlock("s3cret", "203.0.113.7:5678"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected locked client to stay locked on another port, got %d", rec.Code)
+	}
+
+	// ...но не блокирует ссылку для ее настоящего пользователя
+	if rec := unlock("s3cret", "198.51.100.1:4321"); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected another client to unlock, got %d", rec.Code)
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	h := New(nil, slog.New(slog.DiscardHandler), WithTrustedProxies([]netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct", "203.0.113.7:1234", "", "203.0.113.7"},
+		{"untrusted peer ignores header", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:1234", "198.51.100.1", "198.51.100.1"},
+		// Левее доверенных прокси клиент может подставить любой адрес
+		{"spoofed hops", "10.0.0.2:1234", "192.0.2.99, 198.51.100.1, 10.0.0.3", "198.51.100.1"},
+		{"proxy without header", "10.0.0.2:1234", "", "10.0.0.2"},
+		{"ipv6 by /64", "[2001:db8:1:2:3:4:5:6]:1234", "", "2001:db8:1:2::/64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := h.clientAddress(req); got != tt.want {
+				t.Errorf("clientAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreview(t *testing.T) {
+	const destination = `https://example.com/search?q=<script>alert(1)</script>&x="y"`
+	router, svc := newTestRouter(t, service.Config{},
+		&model.URL{ShortCode: "look", OriginalURL: destination},
+	)
+
+	for _, path := range []string{"/look+", "/look?preview=1"} {
+		rec := serve(router, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, rec.Code)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, "Link preview") || !strings.Contains(body, "https://example.com/search?q=&lt;script&gt;") {
+			t.Errorf("%s: expected escaped destination in preview, got %s", path, body)
+		}
+		if strings.Contains(body, "<script>") {
+			t.Errorf("%s: destination must be HTML-escaped", path)
+		}
+		if rec.Header().Get("Location") != "" {
+			t.Errorf("%s: preview must not redirect", path)
+		}
+	}
+
+	stats, err := svc.GetStats(context.Background(), "look")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.ClickCount != 0 {
+		t.Errorf("Expected preview not to count clicks, got %d", stats.ClickCount)
+	}
+}
+
+func TestRedirect_Interstitial(t *testing.T) {
+	const destination = `https://example.com/download?file=<b>"report"</b>`
+	router, svc := newTestRouter(t, service.Config{},
+		&model.URL{ShortCode: "leave", OriginalURL: destination, Interstitial: true, MaxClicks: 1},
+	)
+
+	clicks := func() int64 {
+		t.Helper()
+		stats, err := svc.GetStats(context.Background(), "leave")
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+		return stats.ClickCount
+	}
+
+	// Боты предпросмотра и передумавшие видят страницу, но переход не тратят
+	for range 2 {
+		rec := serve(router, httptest.NewRequest(http.MethodGet, "/leave", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Location") != "" {
+			t.Fatalf("Expected confirmation page, got %d %q", rec.Code, rec.Header().Get("Location"))
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, "You are leaving") || !strings.Contains(body, `href="leave?confirm=1"`) {
+			t.Errorf("Expected confirmation with continue link, got %s", body)
+		}
+		if strings.Contains(body, "<b>") || !strings.Contains(body, "&lt;b&gt;&#34;report&#34;&lt;/b&gt;") {
+			t.Errorf("Expected HTML-escaped destination, got %s", body)
+		}
+	}
+	if got := clicks(); got != 0 {
+		t.Errorf("Expected no clicks before confirmation, got %d", got)
+	}
+
+	rec := serve(router, httptest.NewRequest(http.MethodGet, "/leave?confirm=1", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != destination {
+		t.Fatalf("Expected confirmed redirect, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if got := clicks(); got != 1 {
+		t.Errorf("Expected 1 click after confirmation, got %d", got)
+	}
+
+	// Лимит исчерпан — страница подтверждения больше не показывается
+	for _, path := range []string{"/leave", "/leave?confirm=1"} {
+		if rec := serve(router, httptest.NewRequest(http.MethodGet, path, nil)); rec.Code != http.StatusGone {
+			t.Errorf("%s: expected 410 after the click limit, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestRedirect_InterstitialPassword(t *testing.T) {
+	router, _ := newTestRouter(t, service.Config{}, &model.URL{
+		ShortCode:    "guarded",
+		OriginalURL:  "https://example.com/private",
+		Interstitial: true,
+		PasswordHash: testPasswordHash(t, "s3cret"),
+	})
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("password=s3cret"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(router, req)
+	}
+
+	// После пароля — подтверждение, «Continue» повторяет пароль формой
+	rec := post("/guarded")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `action="guarded?confirm=1"`) {
+		t.Fatalf("Expected confirmation form after unlock, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = post("/guarded?confirm=1")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "https://example.com/private" {
+		t.Errorf("Expected 303 after confirmation, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// Подтверждение не обходит пароль
+	if rec := serve(router, httptest.NewRequest(http.MethodGet, "/guarded?confirm=1", nil)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for confirmation without password, got %d", rec.Code)
+	}
+}
+
+func TestRedirect_StatusAndCaching(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+	router, _ := newTestRouter(t, service.Config{},
+		&model.URL{ShortCode: "r301", OriginalURL: "https://example.com/301", RedirectType: http.StatusMovedPermanently},
+		&model.URL{ShortCode: "r302", OriginalURL: "https://example.com/302"},
+		&model.URL{ShortCode: "r307", OriginalURL: "https://example.com/307", RedirectType: http.StatusTemporaryRedirect},
+		&model.URL{ShortCode: "r308", OriginalURL: "https://example.com/308", RedirectType: http.StatusPermanentRedirect},
+		&model.URL{ShortCode: "expiring", OriginalURL: "https://example.com/exp", RedirectType: http.StatusMovedPermanently, ExpiresAt: &soon},
+		&model.URL{ShortCode: "limited", OriginalURL: "https://example.com/lim", RedirectType: http.StatusPermanentRedirect, MaxClicks: 5},
+	)
+
+	tests := []struct {
+		code         string
+		status       int
+		cacheControl string
+		maxAge       time.Duration // 0 — не кешируется
+	}{
+		{"r301", http.StatusMovedPermanently, "public, max-age=86400", permanentRedirectMaxAge},
+		{"r302", http.StatusFound, "private, no-store", 0},
+		{"r307", http.StatusTemporaryRedirect, "private, no-store", 0},
+		{"r308", http.StatusPermanentRedirect, "public, max-age=86400", permanentRedirectMaxAge},
+		// Кеш не переживает ссылку, а ограниченная по переходам не кешируется вовсе
+		{"expiring", http.StatusMovedPermanently, "", time.Hour},
+		{"limited", http.StatusPermanentRedirect, "private, no-store", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			rec := serve(router, httptest.NewRequest(http.MethodGet, "/"+tt.code, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+
+			cacheControl := rec.Header().Get("Cache-Control")
+			expires := rec.Header().Get("Expires")
+			if tt.maxAge == 0 {
+				if cacheControl != tt.cacheControl || expires != "0" {
+					t.Errorf("Expected uncached response, got Cache-Control %q, Expires %q", cacheControl, expires)
+				}
+				return
+			}
+
+			if tt.cacheControl != "" && cacheControl != tt.cacheControl {
+				t.Errorf("Expected Cache-Control %q, got %q", tt.cacheControl, cacheControl)
+			}
+			var maxAge int
+			if _, err := fmt.Sscanf(cacheControl, "public, max-age=%d", &maxAge); err != nil {
+				t.Fatalf("Unexpected Cache-Control %q", cacheControl)
+			}
+			if got := time.Duration(maxAge) * time.Second; got > tt.maxAge || got < tt.maxAge-time.Minute {
+				t.Errorf("Expected max-age about %s, got %s", tt.maxAge, got)
+			}
+			at, err := http.ParseTime(expires)
+			if err != nil {
+				t.Fatalf("Invalid Expires %q: %v", expires, err)
+			}
+			if d := time.Until(at); d > tt.maxAge || d < tt.maxAge-time.Minute {
+				t.Errorf("Expected Expires in about %s, got %s", tt.maxAge, d)
+			}
+		})
+	}
+}
+
+func TestRedirect_Methods(t *testing.T) {
+	router, _ := newTestRouter(t, service.Config{},
+		&model.URL{ShortCode: "r302", OriginalURL: "https://example.com/302"},
+		&model.URL{ShortCode: "r301", OriginalURL: "https://example.com/301", RedirectType: http.StatusMovedPermanently},
+		&model.URL{ShortCode: "r307", OriginalURL: "https://example.com/307", RedirectType: http.StatusTemporaryRedirect},
+		&model.URL{ShortCode: "r308", OriginalURL: "https://example.com/308", RedirectType: http.StatusPermanentRedirect},
+	)
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodPost, "/r302", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/r301", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/r307", http.StatusTemporaryRedirect},
+		{http.MethodPatch, "/r308", http.StatusPermanentRedirect},
+		{http.MethodPost, "/r307+", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+tt.path, func(t *testing.T) {
+			rec := serve(router, httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"payload":1}`)))
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			if tt.status == http.StatusMethodNotAllowed && rec.Header().Get("Allow") == "" {
+				t.Error("Expected Allow header on 405")
+			}
+		})
+	}
+}
+
+func TestRedirect_ClickLimit(t *testing.T) {
+	router, svc := newTestRouter(t, service.Config{},
+		&model.URL{ShortCode: "once", OriginalURL: "https://example.com/invite", MaxClicks: 1},
+	)
+
+	rec := serve(router, httptest.NewRequest(http.MethodGet, "/once", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://example.com/invite" {
+		t.Fatalf("Expected first visit to redirect, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// Переход учтен синхронно — до ответа
+	stats, err := svc.GetStats(context.Background(), "once")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.ClickCount != 1 {
+		t.Errorf("Expected 1 click after redirect, got %d", stats.ClickCount)
+	}
+
+	for range 2 {
+		rec := serve(router, httptest.NewRequest(http.MethodGet, "/once", nil))
+		if rec.Code != http.StatusGone || rec.Header().Get("Location") != "" {
+			t.Errorf("Expected 410 without redirect, got %d %q", rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
@@ -3,12 +3,15 @@ package handler
 import (
 	"net/http"
 	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
 )
 
 // HealthResponse ответ на health check
 type HealthResponse struct {
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
+	Status    string                `json:"status"`
+	Timestamp string                `json:"timestamp"`
+	Generator model.GenerationStats `json:"generator"`
 }
 
 // Health обрабатывает GET /health
@@ -17,5 +20,6 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusOK, HealthResponse{
 		Status:    "ok",
 		Timestamp: time.Now().Format(time.RFC3339),
+		Generator: h.service.GenerationStats(),
 	})
 }
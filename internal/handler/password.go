@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+	"github.com/dmitrycr/ShortUrl/internal/service"
+)
+
+// maxPasswordBody предел тела запроса с паролем
+const maxPasswordBody = 4 << 10
+
+// passwordPage форма пароля защищенной ссылки; адрес назначения не раскрывается
+// Форма отправляется на /{code} относительной ссылкой — и со страницы предпросмотра
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 4em auto; padding: 0 1em; color: #222; }
+code { word-break: break-all; background: #f4f4f4; padding: 0.2em 0.4em; }
+.error { color: #b00020; }
+input, button { font-size: 1em; padding: 0.5em; }
+</style>
+</head>
+<body>
+<h1>Password required</h1>
+<p>The short link <code>{{.Code}}</code> is protected. Enter the password to continue.</p>
+{{with .Error}}<p class="error">{{.}}</p>
+{{end}}<form method="post" action="{{.Code}}">
+<input type="password" name="password" autocomplete="current-password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+type passwordData struct {
+	Code  string
+	Error string
+}
+
+// passwordRequest тело JSON-запроса с паролем
+type passwordRequest struct {
+	Password string `json:"password"`
+}
+
+// unlock проверяет пароль защищенной ссылки и при отказе отвечает сам;
+// проверенный пароль нужен странице подтверждения, чтобы не спрашивать его снова
+// Пароль принимается только из тела POST: заголовок клиент повторил бы
+// при переходе на адрес назначения, а тело после 303 не повторяется
+func (h *Handler) unlock(w http.ResponseWriter, r *http.Request, url *model.URL) (password string, ok bool) {
+	client := h.clientAddress(r)
+	password = linkPassword(r)
+
+	err := h.service.CheckPassword(r.Context(), url, password, client)
+	switch {
+	case err == nil:
+		return password, true
+	case errors.Is(err, service.ErrPasswordRequired):
+		h.respondPasswordChallenge(w, r, url.ShortCode, http.StatusUnauthorized, "")
+	case errors.Is(err, service.ErrWrongPassword):
+		h.respondPasswordChallenge(w, r, url.ShortCode, http.StatusUnauthorized, "Wrong password.")
+	case errors.Is(err, service.ErrTooManyAttempts):
+		h.logger.Warn("password attempts throttled", "code", url.ShortCode, "client", client)
+		h.respondPasswordChallenge(w, r, url.ShortCode, http.StatusTooManyRequests, "Too many attempts. Try again later.")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// Запрос не дождался очереди проверки паролей
+		h.respondError(w, http.StatusServiceUnavailable, "server is busy, try again later")
+	default:
+		h.logger.Error("failed to check link password", "code", url.ShortCode, "error", err)
+		h.respondError(w, http.StatusInternalServerError, "failed to check password")
+	}
+
+	return "", false
+}
+
+// linkPassword достает пароль из тела POST: JSON {"password": "..."}
+// или поля формы password
+func linkPassword(r *http.Request) string {
+	if r.Method != http.MethodPost {
+		return ""
+	}
+
+	body := io.LimitReader(r.Body, maxPasswordBody)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/json":
+		var req passwordRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			return ""
+		}
+		return req.Password
+	case "application/x-www-form-urlencoded":
+		r.Body = io.NopCloser(body)
+		return r.PostFormValue("password")
+	}
+
+	return ""
+}
+
+// clientAddress адрес клиента для ограничения перебора паролей
+// За доверенными прокси это крайний справа адрес X-Forwarded-For, не
+// принадлежащий прокси: левее клиент может дописать что угодно.
+// IPv6 считается по /64 — столько обычно выдают одному клиенту
+func (h *Handler) clientAddress(r *http.Request) string {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	addr := addrPort.Addr().Unmap()
+
+	if h.trustedProxy(addr) {
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			addr = hop.Unmap()
+			if !h.trustedProxy(addr) {
+				break
+			}
+		}
+	}
+
+	if addr.Is6() {
+		prefix, _ := addr.Prefix(64)
+		return prefix.String()
+	}
+	return addr.String()
+}
+
+func (h *Handler) trustedProxy(addr netip.Addr) bool {
+	for _, proxy := range h.trustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// respondPasswordChallenge просит пароль: браузерам — HTML-формой, API-клиентам — JSON
+func (h *Handler) respondPasswordChallenge(w http.ResponseWriter, r *http.Request, code string, status int, message string) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		if message == "" {
+			message = "password required"
+		}
+		h.respondError(w, status, strings.TrimSuffix(strings.ToLower(message), "."))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := passwordPage.Execute(w, passwordData{Code: code, Error: message}); err != nil {
+		h.logger.Error("failed to render password page", "error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+	"github.com/dmitrycr/ShortUrl/internal/service"
+)
+
+// previewPage показывает, куда ведет короткая ссылка, до перехода по ней
+// Та же страница служит подтверждением для ссылок с флагом interstitial
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{if .Confirm}}You are leaving{{else}}Link preview{{end}}</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 4em auto; padding: 0 1em; color: #222; }
+code { word-break: break-all; background: #f4f4f4; padding: 0.2em 0.4em; }
+dt { color: #666; margin-top: 0.8em; }
+dd { margin: 0.2em 0 0; }
+.continue { display: inline-block; border: 0; font-size: 1em; cursor: pointer; margin-top: 1.5em; padding: 0.6em 1.2em; background: #1a73e8; color: #fff; text-decoration: none; border-radius: 4px; }
+</style>
+</head>
+<body>
+{{if .Confirm}}<h1>You are leaving</h1>
+<p>The short link <code>{{.Code}}</code> asks for confirmation before redirecting. Check the destination before you continue.</p>
+{{else}}<h1>Link preview</h1>
+<p>The short link <code>{{.Code}}</code> leads to:</p>
+{{end}}<dl>
+<dt>Destination</dt>
+<dd><code>{{.URL}}</code></dd>
+<dt>Created</dt>
+<dd>{{.CreatedAt.UTC.Format "2006-01-02 15:04 MST"}}</dd>
+{{with .ExpiresAt}}<dt>Expires</dt>
+<dd>{{.UTC.Format "2006-01-02 15:04 MST"}}</dd>
+{{end}}<dt>Clicks</dt>
+<dd>{{.ClickCount}}</dd>
+</dl>
+{{if not .Confirm}}<a class="continue" href="{{.URL}}" rel="noopener noreferrer nofollow">Continue to site</a>
+{{else if .Password}}<form method="post" action="{{.Code}}?confirm=1">
+<input type="hidden" name="password" value="{{.Password}}">
+<button class="continue" type="submit">Continue to site</button>
+</form>
+{{else}}<a class="continue" href="{{.Code}}?confirm=1" rel="nofollow">Continue to site</a>
+{{end}}
+</body>
+</html>
+`))
+
+type previewData struct {
+	Code       string
+	URL        string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	ClickCount int64
+	Confirm    bool // страница подтверждения, а не предпросмотр
+
+	// Пароль защищенной ссылки: «Continue» повторяет его формой, а не спрашивает снова
+	Password string
+}
+
+// Preview обрабатывает GET /{code}+ и /{code}?preview=1
+// Показывает адрес назначения, дату создания и число переходов без редиректа
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request, shortCode string) {
+	if err := h.service.VerifyCode(shortCode); err != nil {
+		h.respondMistypedCode(w, r, shortCode)
+		return
+	}
+
+	stats, err := h.service.GetStats(r.Context(), shortCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrURLNotFound):
+			h.respondNotFound(w, r, shortCode)
+		default:
+			h.respondError(w, http.StatusInternalServerError, "failed to get stats")
+		}
+		return
+	}
+	shortCode = stats.ShortCode
+
+	if stats.ExpiresAt != nil && stats.ExpiresAt.Before(time.Now()) {
+		h.respondError(w, http.StatusGone, "this short URL has expired")
+		return
+	}
+	if stats.MaxClicks > 0 && stats.ClickCount >= stats.MaxClicks {
+		h.respondError(w, http.StatusGone, "this short URL has reached its click limit")
+		return
+	}
+
+	// Адрес защищенной ссылки раскрывается только после пароля — форма ведет на /{code}
+	if stats.PasswordProtected {
+		h.respondPasswordChallenge(w, r, shortCode, http.StatusUnauthorized, "")
+		return
+	}
+
+	// Вредоносный адрес не показываем ссылкой даже в предпросмотре
+	if match, flagged := h.service.CheckThreat(stats.OriginalURL); flagged {
+		h.respondThreatWarning(w, shortCode, stats.OriginalURL, match)
+		return
+	}
+
+	h.respondPreview(w, previewData{
+		Code:       shortCode,
+		URL:        stats.OriginalURL,
+		CreatedAt:  stats.CreatedAt,
+		ExpiresAt:  stats.ExpiresAt,
+		ClickCount: stats.ClickCount,
+	})
+}
+
+// respondInterstitial показывает страницу подтверждения вместо редиректа
+// «Continue» ведет на /{code}?confirm=1 — переход засчитывается там
+func (h *Handler) respondInterstitial(w http.ResponseWriter, url *model.URL, password string) {
+	h.respondPreview(w, previewData{
+		Code:       url.ShortCode,
+		URL:        url.OriginalURL,
+		CreatedAt:  url.CreatedAt,
+		ExpiresAt:  url.ExpiresAt,
+		ClickCount: url.ClickCount,
+		Confirm:    true,
+		Password:   password,
+	})
+}
+
+func (h *Handler) respondPreview(w http.ResponseWriter, data previewData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if err := previewPage.Execute(w, data); err != nil {
+		h.logger.Error("failed to render preview page", "error", err)
+	}
+}
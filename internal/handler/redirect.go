@@ -2,14 +2,26 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/dmitrycr/ShortUrl/internal/model"
 	"github.com/dmitrycr/ShortUrl/internal/service"
 	"github.com/go-chi/chi/v5"
 )
 
-// Redirect обрабатывает GET /{code}
-// Перенаправляет пользователя на оригинальный URL
+// permanentRedirectMaxAge сколько браузеры и прокси кешируют постоянный редирект
+// Срок ограничен, чтобы удаление ссылки и обновление списков угроз доходили до клиентов
+const permanentRedirectMaxAge = 24 * time.Hour
+
+// Redirect обрабатывает GET /{code} (и POST, PUT, PATCH для ссылок с 307/308,
+// POST для защищенных паролем; остальным — 405)
+// Перенаправляет пользователя на оригинальный URL со статусом, заданным для ссылки;
+// /{code}+ и ?preview=1 показывают страницу предпросмотра, ссылки с interstitial —
+// страницу подтверждения, с которой переход идет на /{code}?confirm=1
 func (h *Handler) Redirect(w http.ResponseWriter, r *http.Request) {
 	// Получаем код из URL
 	shortCode := chi.URLParam(r, "code")
@@ -18,12 +30,30 @@ func (h *Handler) Redirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Получаем оригинальный URL
-	originalURL, err := h.service.GetOriginalURL(r.Context(), shortCode)
+	// Символа + нет в алфавите кодов — он не спутается с частью кода
+	code, preview := strings.CutSuffix(shortCode, "+")
+	if preview || r.URL.Query().Get("preview") == "1" {
+		if r.Method != http.MethodGet {
+			h.respondMethodNotAllowed(w, []string{http.MethodGet})
+			return
+		}
+		h.Preview(w, r, code)
+		return
+	}
+
+	// Опечатку в коде вида сгенерированного видно по контрольному символу —
+	// в хранилище за ней не идем
+	if err := h.service.VerifyCode(shortCode); err != nil {
+		h.respondMistypedCode(w, r, shortCode)
+		return
+	}
+
+	// Получаем ссылку
+	url, err := h.service.GetURL(r.Context(), shortCode)
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrURLNotFound):
-			h.respondError(w, http.StatusNotFound, "short URL not found")
+			h.respondNotFound(w, r, shortCode)
 		case errors.Is(err, service.ErrURLExpired):
 			h.respondError(w, http.StatusGone, "this short URL has expired")
 		default:
@@ -31,19 +61,151 @@ func (h *Handler) Redirect(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	// Код мог быть введен не в каноническом виде (crockford: o вместо 0)
+	shortCode = url.ShortCode
+	originalURL := url.OriginalURL
+
+	if allowed := allowedMethods(url); !slices.Contains(allowed, r.Method) {
+		h.respondMethodNotAllowed(w, allowed)
+		return
+	}
+
+	// Пароль проверяется раньше всего, что раскрывает адрес назначения
+	status := url.RedirectStatus()
+	var password string
+	if url.PasswordHash != "" {
+		var ok bool
+		if password, ok = h.unlock(w, r, url); !ok {
+			return
+		}
+		// Пароль пришел в теле: при 307/308 клиент отправил бы его на адрес назначения
+		status = http.StatusSeeOther
+	}
+
+	// Списки угроз обновляются — проверяем и уже созданные ссылки
+	if match, flagged := h.service.CheckThreat(originalURL); flagged {
+		h.logger.Warn("blocked redirect to flagged url",
+			"code", shortCode,
+			"threat_type", match.ThreatType,
+			"expression", match.Expression,
+		)
+		h.respondThreatWarning(w, shortCode, originalURL, match)
+		return
+	}
+
+	// Страница подтверждения ничего не засчитывает: переход учитывается, только
+	// когда пользователь нажал «Continue» (?confirm=1). Боты предпросмотра и
+	// передумавшие не тратят одноразовые ссылки
+	if url.Interstitial && r.URL.Query().Get("confirm") != "1" {
+		if url.MaxClicks > 0 && url.ClickCount >= url.MaxClicks {
+			h.respondError(w, http.StatusGone, "this short URL has reached its click limit")
+			return
+		}
+		h.respondInterstitial(w, url, password)
+		return
+	}
+
+	if url.MaxClicks > 0 {
+		// Лимит проверяется до редиректа: переход учитывается синхронно
+		if err := h.service.ConsumeClick(r.Context(), shortCode); err != nil {
+			switch {
+			case errors.Is(err, service.ErrClicksExhausted):
+				h.respondError(w, http.StatusGone, "this short URL has reached its click limit")
+			case errors.Is(err, service.ErrURLExpired):
+				h.respondError(w, http.StatusGone, "this short URL has expired")
+			case errors.Is(err, service.ErrURLNotFound):
+				h.respondError(w, http.StatusNotFound, "short URL not found")
+			default:
+				h.logger.Error("failed to consume click", "code", shortCode, "error", err)
+				h.respondError(w, http.StatusInternalServerError, "failed to resolve URL")
+			}
+			return
+		}
+	} else {
+		// Регистрируем клик асинхронно — не задерживаем редирект
+		go func() {
+			if err := h.service.RegisterClick(r.Context(), shortCode); err != nil {
+				h.logger.Error("failed to register click",
+					"code", shortCode,
+					"error", err,
+				)
+			}
+		}()
+	}
+
+	setRedirectCacheHeaders(w, url)
+	http.Redirect(w, r, originalURL, status)
+}
+
+// allowedMethods методы, которыми можно открыть ссылку
+// POST, PUT, PATCH повторяются на адрес назначения только при 307/308;
+// POST нужен и защищенным паролем — им отправляется форма пароля
+func allowedMethods(url *model.URL) []string {
+	switch {
+	case url.RedirectStatus() == http.StatusTemporaryRedirect, url.RedirectStatus() == http.StatusPermanentRedirect:
+		return []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch}
+	case url.PasswordHash != "":
+		return []string{http.MethodGet, http.MethodPost}
+	default:
+		return []string{http.MethodGet}
+	}
+}
 
-	// Регистрируем клик асинхронно — не задерживаем редирект
-	go func() {
-		if err := h.service.RegisterClick(r.Context(), shortCode); err != nil {
-			h.logger.Error("failed to register click",
-				"code", shortCode,
-				"error", err,
-			)
+func (h *Handler) respondMethodNotAllowed(w http.ResponseWriter, allowed []string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	h.respondError(w, http.StatusMethodNotAllowed, "method not allowed for this short URL")
+}
+
+// setRedirectCacheHeaders задает кеширование по типу редиректа
+//
+// 301, 308 — постоянные: кешируются, но не дольше срока жизни ссылки; пока редирект
+// в кеше, переходы не доходят до сервиса и не считаются.
+// 302, 307 — временные: не кешируются, каждый переход учитывается.
+// Защищенные паролем и ограниченные по переходам не кешируются никогда:
+// из кеша они открылись бы без пароля и сверх лимита.
+// Expires дублирует срок для кешей, не понимающих Cache-Control
+func setRedirectCacheHeaders(w http.ResponseWriter, url *model.URL) {
+	now := time.Now()
+
+	maxAge := time.Duration(0)
+	if url.PasswordHash == "" && url.MaxClicks == 0 {
+		switch url.RedirectStatus() {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			maxAge = permanentRedirectMaxAge
+			if url.ExpiresAt != nil {
+				maxAge = min(maxAge, url.ExpiresAt.Sub(now))
+			}
 		}
-	}()
+	}
+
+	if seconds := int(maxAge.Seconds()); seconds > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", seconds))
+		w.Header().Set("Expires", now.Add(time.Duration(seconds)*time.Second).UTC().Format(http.TimeFormat))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("Expires", "0")
+}
+
+// respondNotFound отвечает 404 на промах в хранилище; если контрольный символ
+// кода не сходится (режим CODE_CHECKSUM_LEGACY), это опечатка — с подсказками
+func (h *Handler) respondNotFound(w http.ResponseWriter, r *http.Request, code string) {
+	if !h.service.LooksMistyped(code) {
+		h.respondError(w, http.StatusNotFound, "short URL not found")
+		return
+	}
+
+	h.respondMistypedCode(w, r, code)
+}
+
+// respondMistypedCode отвечает 404 и предлагает существующие коды,
+// отличающиеся от введенного одним символом
+func (h *Handler) respondMistypedCode(w http.ResponseWriter, r *http.Request, code string) {
+	suggestions, err := h.service.SuggestCorrections(r.Context(), code)
+	if err != nil {
+		h.logger.Warn("failed to suggest code corrections", "code", code, "error", err)
+	}
 
-	// 301 — постоянный редирект (кешируется браузером)
-	// 302 — временный редирект (не кешируется)
-	// Для счетчика кликов лучше 302
-	http.Redirect(w, r, originalURL, http.StatusFound)
+	h.respondErrorWithSuggestions(w, http.StatusNotFound, "short URL not found", suggestions)
 }
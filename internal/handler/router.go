@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -39,19 +40,56 @@ func NewRouter(h *Handler) http.Handler {
 		// Создание короткой ссылки
 		r.Post("/shorten", h.Shorten)
 
+		// Проверка кастомного кода
+		r.Get("/codes/{code}/availability", h.CodeAvailability)
+
 		// Статистика
 		r.Get("/stats/{code}", h.GetStats)
 
+		// Ссылки по состоянию целевого URL
+		r.Get("/urls", h.ListURLs)
+
 		// Удаление
 		r.Delete("/urls/{code}", h.Delete)
 	})
 
 	// Редирект — должен быть последним
+	// Не-GET методы нужны ссылкам 307/308: клиент повторяет запрос с тем же методом и телом
+	// Ссылка известна только в обработчике — он и отвечает 405 остальным (allowedMethods)
 	r.Get("/{code}", h.Redirect)
+	r.Post("/{code}", h.Redirect)
+	r.Put("/{code}", h.Redirect)
+	r.Patch("/{code}", h.Redirect)
 
 	return r
 }
 
+// RouteWords возвращает первые сегменты путей роутера (health, api, ...)
+// Короткий код с таким именем перекрыл бы маршрут, поэтому они резервируются
+func RouteWords(router http.Handler) []string {
+	routes, ok := router.(chi.Routes)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var words []string
+
+	chi.Walk(routes, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment == "" || strings.HasPrefix(segment, "{") {
+			return nil
+		}
+		if _, dup := seen[segment]; !dup {
+			seen[segment] = struct{}{}
+			words = append(words, segment)
+		}
+		return nil
+	})
+
+	return words
+}
+
 // securityHeaders добавляет базовые заголовки безопасности
 func securityHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
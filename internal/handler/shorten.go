@@ -26,9 +26,31 @@ func (h *Handler) Shorten(w http.ResponseWriter, r *http.Request) {
 
 		switch {
 		case errors.Is(err, service.ErrInvalidURL):
-			h.respondError(w, http.StatusBadRequest, "invalid URL provided")
+			// Причина (схема, внутренний адрес...) помогает исправить запрос
+			h.respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrDomainForbidden):
+			h.respondError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, service.ErrURLMalicious):
+			h.respondError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, service.ErrSelfReferential), errors.Is(err, service.ErrShortenerChain):
+			h.respondError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, service.ErrInvalidRedirect), errors.Is(err, service.ErrInvalidPassword),
+			errors.Is(err, service.ErrInvalidMaxClicks):
+			h.respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrClickLimitDisabled):
+			h.respondError(w, http.StatusNotImplemented, "click limits are not supported by this storage")
+		case errors.Is(err, service.ErrCodeMistyped):
+			h.respondError(w, http.StatusBadRequest, "custom code looks generated but its check character is wrong")
+		case errors.Is(err, service.ErrInvalidCode):
+			h.respondError(w, http.StatusBadRequest, "invalid custom code")
+		case errors.Is(err, service.ErrCodeBlocked):
+			h.respondError(w, http.StatusUnprocessableEntity, "this custom code is not allowed")
+		case errors.Is(err, service.ErrCodeReserved):
+			h.respondError(w, http.StatusUnprocessableEntity, "this custom code is reserved")
 		case errors.Is(err, service.ErrCodeAlreadyUsed):
-			h.respondError(w, http.StatusConflict, "this custom code is already taken")
+			h.respondCodeConflict(w, r, req.CustomCode)
+		case errors.Is(err, service.ErrCodeExhausted):
+			h.respondError(w, http.StatusServiceUnavailable, "failed to allocate short code, please retry")
 		default:
 			h.respondError(w, http.StatusInternalServerError, "failed to shorten URL")
 		}
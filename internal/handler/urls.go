@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+	"github.com/dmitrycr/ShortUrl/internal/service"
+)
+
+// URLListResponse список ссылок с их статистикой
+type URLListResponse struct {
+	URLs  []model.Stats `json:"urls"`
+	Count int           `json:"count"`
+}
+
+// ListURLs обрабатывает GET /api/urls?health=broken[&limit=N]
+// Возвращает коды ссылок с битым целевым URL и статус проверки, без адресов
+// назначения; ссылки с паролем и лимитом переходов в список не попадают.
+// Другие состояния не отдаются: эндпоинт без авторизации
+func (h *Handler) ListURLs(w http.ResponseWriter, r *http.Request) {
+	health := r.URL.Query().Get("health")
+	if health == "" {
+		h.respondError(w, http.StatusBadRequest, "health filter is required: broken")
+		return
+	}
+
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			h.respondError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = n
+	}
+
+	urls, err := h.service.ListURLsByHealth(r.Context(), health, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidHealth):
+			h.respondError(w, http.StatusBadRequest, "health filter must be broken")
+		case errors.Is(err, service.ErrHealthDisabled):
+			h.respondError(w, http.StatusNotImplemented, "link health is not tracked by this storage")
+		default:
+			h.logger.Error("failed to list urls", "health", health, "error", err)
+			h.respondError(w, http.StatusInternalServerError, "failed to list URLs")
+		}
+		return
+	}
+
+	if urls == nil {
+		urls = []model.Stats{}
+	}
+
+	h.respondJSON(w, http.StatusOK, URLListResponse{URLs: urls, Count: len(urls)})
+}
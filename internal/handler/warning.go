@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/dmitrycr/ShortUrl/pkg/threatlist"
+)
+
+// warningPage страница вместо редиректа на URL из списка вредоносных
+// Адрес выводится текстом, а не ссылкой, чтобы по нему не перешли случайно
+var warningPage = template.Must(template.New("warning").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Warning: unsafe link</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 4em auto; padding: 0 1em; color: #222; }
+h1 { color: #b00020; }
+code { word-break: break-all; background: #f4f4f4; padding: 0.2em 0.4em; }
+</style>
+</head>
+<body>
+<h1>This link has been blocked</h1>
+<p>The short link <code>{{.Code}}</code> leads to a site reported as <strong>{{.ThreatType}}</strong>.
+Visiting it may put your device or personal data at risk.</p>
+<p>Destination: <code>{{.URL}}</code></p>
+</body>
+</html>
+`))
+
+type warningData struct {
+	Code       string
+	URL        string
+	ThreatType string
+}
+
+// respondThreatWarning показывает предупреждение вместо редиректа
+func (h *Handler) respondThreatWarning(w http.ResponseWriter, code, originalURL string, match threatlist.Match) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusForbidden)
+
+	err := warningPage.Execute(w, warningData{
+		Code:       code,
+		URL:        originalURL,
+		ThreatType: match.ThreatType,
+	})
+	if err != nil {
+		h.logger.Error("failed to render warning page", "error", err)
+	}
+}
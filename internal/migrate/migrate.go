@@ -0,0 +1,450 @@
+// Package migrate применяет версионированные SQL-миграции
+// и ведет учет примененных версий в таблице schema_migrations
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidFilename  = errors.New("invalid migration filename")
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+	ErrNoDownMigration  = errors.New("migration has no down script")
+)
+
+// Dialect определяет особенности конкретной СУБД
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// lockKey ключ advisory lock в Postgres, общий для всех экземпляров сервиса
+const lockKey = 7_345_129_004
+
+// featureDirective первая строка up-скрипта, привязывающая миграцию к функции:
+// -- migrate:feature <имя>
+const featureDirective = "-- migrate:feature "
+
+// Migration одна версия схемы
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+
+	// Feature функция, для которой нужна миграция (пусто — нужна всегда)
+	// Такие миграции применяются, только если функция включена (см. WithFeature)
+	Feature string
+}
+
+// Status состояние миграции в базе
+type Status struct {
+	Migration
+	AppliedAt *time.Time // nil — не применена
+}
+
+// Migrator применяет и откатывает миграции
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+
+	// features включенные (true) и выключенные (false) функции;
+	// миграции функций, которых здесь нет, не трогаются
+	features map[string]bool
+}
+
+// Option настраивает Migrator
+type Option func(*Migrator)
+
+// WithFeature включает или выключает миграции функции name: Up применяет
+// миграции включенной функции, RevertDisabled откатывает миграции выключенной
+// Без этой опции миграции функции пропускаются, а примененные остаются
+func WithFeature(name string, enabled bool) Option {
+	return func(m *Migrator) {
+		m.features[name] = enabled
+	}
+}
+
+// New создает мигратор; migrations должны быть отсортированы (см. Load)
+func New(db *sql.DB, dialect Dialect, migrations []Migration, opts ...Option) *Migrator {
+	m := &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+		features:   make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Load читает миграции из директории dir
+// Имена файлов: 001_init.up.sql и 001_init.down.sql
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		version, name, direction, err := parseFilename(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("%w: %d (%s, %s)", ErrDuplicateVersion, version, m.Name, name)
+		}
+
+		switch direction {
+		case "up":
+			if m.Up != "" {
+				return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+			}
+			m.Up = string(content)
+			m.Feature = parseFeature(m.Up)
+		case "down":
+			if m.Down != "" {
+				return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+			}
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: %d_%s has no up script", ErrInvalidFilename, m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// parseFilename разбирает 001_init.up.sql -> (1, "init", "up")
+func parseFilename(filename string) (int64, string, string, error) {
+	base := strings.TrimSuffix(filename, ".sql")
+
+	dot := strings.LastIndex(base, ".")
+	if dot == -1 {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFilename, filename)
+	}
+	base, direction := base[:dot], base[dot+1:]
+	if direction != "up" && direction != "down" {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFilename, filename)
+	}
+
+	number, name, found := strings.Cut(base, "_")
+	if !found || name == "" {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFilename, filename)
+	}
+
+	version, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("%w: %s", ErrInvalidFilename, filename)
+	}
+
+	return version, name, direction, nil
+}
+
+// parseFeature возвращает функцию из директивы в первой строке up-скрипта
+func parseFeature(up string) string {
+	line, _, _ := strings.Cut(up, "\n")
+	feature, ok := strings.CutPrefix(strings.TrimSpace(line), featureDirective)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(feature)
+}
+
+// Up применяет все неприменённые миграции и возвращает их список
+// Миграции функций применяются, только если функция включена (WithFeature)
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		for _, migration := range m.migrations {
+			if migration.Feature != "" && !m.features[migration.Feature] {
+				continue
+			}
+
+			ok, err := m.apply(ctx, conn, migration)
+			if err != nil {
+				return err
+			}
+			if ok {
+				applied = append(applied, migration)
+			}
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down откатывает последние steps примененных миграций
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// RevertDisabled откатывает примененные миграции функций, выключенных
+// через WithFeature, — так схема следует за выключением функции
+func (m *Migrator) RevertDisabled(ctx context.Context) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if enabled, known := m.features[migration.Feature]; migration.Feature == "" || !known || enabled {
+				continue
+			}
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status возвращает все известные миграции с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		if err := m.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if appliedAt, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+
+	return statuses, err
+}
+
+// apply применяет миграцию в транзакции, если она еще не применена
+// Повторная проверка внутри транзакции защищает от гонки без advisory lock (SQLite)
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) (bool, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM schema_migrations WHERE version = "+m.placeholder(1),
+		migration.Version,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %d: %w", migration.Version, err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return false, fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO schema_migrations (version, name, applied_at) VALUES (%s, %s, %s)",
+			m.placeholder(1), m.placeholder(2), m.placeholder(3)),
+		migration.Version, migration.Name, time.Now().UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+
+	return true, nil
+}
+
+// revert откатывает миграцию в транзакции
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM schema_migrations WHERE version = "+m.placeholder(1),
+		migration.Version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to unrecord migration %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit revert %d: %w", migration.Version, err)
+	}
+
+	return nil
+}
+
+// appliedVersions возвращает версии из schema_migrations с временем применения
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+// withLock выполняет fn на одном подключении под межпроцессной блокировкой
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	return m.withConn(ctx, func(conn *sql.Conn) error {
+		if m.dialect == Postgres {
+			if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+				return fmt.Errorf("failed to acquire migration lock: %w", err)
+			}
+			defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+		}
+
+		if err := m.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+
+		return fn(conn)
+	})
+}
+
+// withConn выполняет fn на выделенном подключении
+// Advisory lock в Postgres принадлежит сессии, поэтому все шаги идут через одно подключение
+func (m *Migrator) withConn(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	return fn(conn)
+}
+
+// ensureTable создает таблицу учета миграций
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// placeholder возвращает плейсхолдер параметра n для диалекта
+func (m *Migrator) placeholder(n int) string {
+	if m.dialect == Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/002_add_index.up.sql": {Data: []byte("CREATE INDEX i ON t(a);")},
+			"m/001_init.up.sql":      {Data: []byte("CREATE TABLE t (a TEXT);")},
+			"m/001_init.down.sql":    {Data: []byte("DROP TABLE t;")},
+		}
+
+		migrations, err := Load(fsys, "m")
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+
+		if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Version != 2 {
+			t.Fatalf("Unexpected migrations: %+v", migrations)
+		}
+		if migrations[0].Down == "" {
+			t.Error("Expected down script for 001")
+		}
+	})
+
+	t.Run("Feature directive", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/001_init.up.sql":  {Data: []byte("CREATE TABLE t (a TEXT);")},
+			"m/002_lower.up.sql": {Data: []byte("-- migrate:feature lower_a\nCREATE INDEX i ON t(lower(a));")},
+		}
+
+		migrations, err := Load(fsys, "m")
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+
+		if migrations[0].Feature != "" || migrations[1].Feature != "lower_a" {
+			t.Errorf("Unexpected features: %q, %q", migrations[0].Feature, migrations[1].Feature)
+		}
+	})
+
+	t.Run("Invalid filename", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/init.sql": {Data: []byte("SELECT 1;")},
+		}
+
+		if _, err := Load(fsys, "m"); !errors.Is(err, ErrInvalidFilename) {
+			t.Errorf("Expected ErrInvalidFilename, got %v", err)
+		}
+	})
+}
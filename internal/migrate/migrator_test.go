@@ -0,0 +1,118 @@
+//go:build cgo
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrations, err := Load(fstest.MapFS{
+		"m/001_init.up.sql":    {Data: []byte("CREATE TABLE t (a TEXT);")},
+		"m/001_init.down.sql":  {Data: []byte("DROP TABLE t;")},
+		"m/002_add_b.up.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN b TEXT;")},
+		"m/002_add_b.down.sql": {Data: []byte("ALTER TABLE t DROP COLUMN b;")},
+		"m/003_no_down.up.sql": {Data: []byte("CREATE TABLE u (a TEXT);")},
+	}, "m")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	m := New(db, SQLite, migrations)
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != 3 {
+		t.Errorf("Expected 3 applied migrations, got %d", len(applied))
+	}
+
+	// Повторный запуск ничего не применяет
+	applied, err = m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Second Up failed: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Expected 0 applied migrations, got %d", len(applied))
+	}
+
+	// У 003 нет down-скрипта
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrNoDownMigration) {
+		t.Errorf("Expected ErrNoDownMigration, got %v", err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("Expected migration %d to be applied", s.Version)
+		}
+	}
+}
+
+func TestMigrator_Features(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrations, err := Load(fstest.MapFS{
+		"m/001_init.up.sql":    {Data: []byte("CREATE TABLE t (a TEXT);")},
+		"m/002_lower.up.sql":   {Data: []byte("-- migrate:feature lower_a\nCREATE UNIQUE INDEX i ON t (lower(a));")},
+		"m/002_lower.down.sql": {Data: []byte("DROP INDEX i;")},
+	}, "m")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	indexExists := func() bool {
+		var n int
+		if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'index' AND name = 'i'").Scan(&n); err != nil {
+			t.Fatalf("Failed to check index: %v", err)
+		}
+		return n > 0
+	}
+
+	// Без опции миграция функции пропускается
+	applied, err := New(db, SQLite, migrations).Up(ctx)
+	if err != nil || len(applied) != 1 || indexExists() {
+		t.Fatalf("Expected only 001 to be applied, got %v, %v", applied, err)
+	}
+
+	applied, err = New(db, SQLite, migrations, WithFeature("lower_a", true)).Up(ctx)
+	if err != nil || len(applied) != 1 || applied[0].Version != 2 || !indexExists() {
+		t.Fatalf("Expected feature migration to be applied, got %v, %v", applied, err)
+	}
+
+	// Без опции примененная миграция функции остается
+	reverted, err := New(db, SQLite, migrations).RevertDisabled(ctx)
+	if err != nil || len(reverted) != 0 || !indexExists() {
+		t.Fatalf("Expected nothing to be reverted, got %v, %v", reverted, err)
+	}
+
+	reverted, err = New(db, SQLite, migrations, WithFeature("lower_a", false)).RevertDisabled(ctx)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 || indexExists() {
+		t.Fatalf("Expected feature migration to be reverted, got %v, %v", reverted, err)
+	}
+}
@@ -10,6 +10,9 @@ var (
 	// MaxCustomCodeLength максимальная длина кастомного кода
 	MaxCustomCodeLength = 20
 
+	// DefaultRedirectType статус редиректа по умолчанию: временный, клики считаются
+	DefaultRedirectType = 302
+
 	// DefaultExpirationDays срок действия по умолчанию (0 = бессрочно)
 	DefaultExpirationDays = 0
 )
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+// Состояния целевого URL по результату последней проверки
+const (
+	HealthUnchecked = "unchecked"
+	HealthOK        = "ok"
+	HealthBroken    = "broken"
+)
+
+// HealthOf возвращает состояние по статусу последней проверки
+// Битой считается ссылка, чей адрес недоступен (0), удален (404, 410) или
+// отвечает ошибкой сервера. 401, 403, 429 означают, что страница существует,
+// но не пускает проверку
+func HealthOf(status int, checkedAt *time.Time) string {
+	if checkedAt == nil {
+		return HealthUnchecked
+	}
+
+	switch {
+	case status == 0, status == http.StatusNotFound, status == http.StatusGone, status >= 500:
+		return HealthBroken
+	default:
+		return HealthOK
+	}
+}
@@ -9,15 +9,49 @@ type URL struct {
 	CreatedAt   time.Time  `db:"created_at"`
 	ExpiresAt   *time.Time `db:"expires_at"`
 	ClickCount  int64      `db:"click_count"`
+
+	// RedirectType HTTP-статус редиректа: 301, 302, 307 или 308 (0 = 302)
+	RedirectType int `db:"redirect_type"`
+
+	// Interstitial показывать страницу подтверждения вместо немедленного редиректа
+	Interstitial bool `db:"interstitial"`
+
+	// PasswordHash хеш пароля ссылки; пусто — переход без пароля
+	PasswordHash string `db:"password_hash"`
+
+	// MaxClicks сколько переходов разрешено (0 — без ограничения)
+	MaxClicks int64 `db:"max_clicks"`
+
+	// Результат последней проверки целевого URL (0 — запрос не удался)
+	LastStatus    int        `db:"last_status"`
+	LastCheckedAt *time.Time `db:"last_checked_at"`
+}
+
+// RedirectStatus возвращает HTTP-статус редиректа; ссылки без типа — 302
+func (u *URL) RedirectStatus() int {
+	if u.RedirectType == 0 {
+		return DefaultRedirectType
+	}
+	return u.RedirectType
 }
 
 // Stats - статистика оп ссылке
 type Stats struct {
-	OriginalURL string     `json:"original_url"`
+	OriginalURL string     `json:"original_url,omitempty"` // пусто у защищенных паролем
 	ShortCode   string     `json:"short_url"`
 	CreatedAt   time.Time  `json:"created_at"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 	ClickCount  int64      `json:"click_count"`
+
+	RedirectType      int   `json:"redirect_type"`
+	Interstitial      bool  `json:"interstitial"`
+	PasswordProtected bool  `json:"password_protected"`
+	MaxClicks         int64 `json:"max_clicks,omitempty"`
+
+	// Состояние целевого URL по последней проверке: ok, broken, unchecked
+	Health        string     `json:"health"`
+	LastStatus    int        `json:"last_status,omitempty"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
 }
 
 // CreateURLRequest - запрос на создание короткой ссылки
@@ -25,6 +59,18 @@ type CreateURLRequest struct {
 	URL        string `json:"url" validate:"required,url"`
 	CustomCode string `json:"custom_code,omitempty"` // опционально
 	ExpiresIn  int    `json:"expires_in,omitempty"`  // В секундах
+
+	// RedirectType статус редиректа: 301, 308 — постоянный, 302 (по умолчанию), 307 — с сохранением метода
+	RedirectType int `json:"redirect_type,omitempty"`
+
+	// Interstitial переход только после подтверждения на странице с адресом назначения
+	Interstitial bool `json:"interstitial,omitempty"`
+
+	// Password пароль для перехода; хранится только хеш
+	Password string `json:"password,omitempty"`
+
+	// MaxClicks после стольких переходов ссылка перестает работать (1 — одноразовая)
+	MaxClicks int64 `json:"max_clicks,omitempty"`
 }
 
 // CreateURLResponse - ответ при создании короткой ссылки
@@ -33,4 +79,46 @@ type CreateURLResponse struct {
 	ShortCode   string     `json:"short_code"`
 	OriginalURL string     `json:"original_url"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	RedirectType      int   `json:"redirect_type"`
+	Interstitial      bool  `json:"interstitial"`
+	PasswordProtected bool  `json:"password_protected"`
+	MaxClicks         int64 `json:"max_clicks,omitempty"`
+}
+
+// CodeAvailability - можно ли занять кастомный код
+type CodeAvailability struct {
+	Code      string `json:"code"`
+	Available bool   `json:"available"`
+
+	// Reason почему код недоступен: invalid, reserved, blocked, taken
+	Reason string `json:"reason,omitempty"`
+
+	// Suggestions свободные похожие коды
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// GenerationStats - счетчики генерации коротких кодов
+// Рост доли коллизий означает, что пространство ключей заполняется
+type GenerationStats struct {
+	Generated  int64 `json:"generated"`
+	Collisions int64 `json:"collisions"`
+	Exhausted  int64 `json:"exhausted"`
+
+	// CodeLength текущая длина случайных кодов (растет при заполнении);
+	// 0 — словесные коды переменной длины
+	CodeLength int `json:"code_length,omitempty"`
+
+	// CollisionRate скользящая доля коллизий — оценка заполнения пространства кодов
+	CollisionRate float64 `json:"collision_rate"`
+
+	// KeyspaceSize количество кодов текущей длины
+	KeyspaceSize float64 `json:"keyspace_size"`
+
+	// Utilization доля занятых кодов текущей длины по данным хранилища;
+	// nil — хранилище не считает коды или длина фиксирована
+	Utilization *float64 `json:"utilization,omitempty"`
+
+	// PoolAvailable кодов в пуле; nil — пул выключен
+	PoolAvailable *int `json:"pool_available,omitempty"`
 }
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/dmitrycr/ShortUrl/internal/validator"
+)
+
+// DefaultShortenerHosts известные сокращатели ссылок; шаблоны как в validator.DomainSet
+var DefaultShortenerHosts = []string{
+	".bit.ly", ".bitly.com", ".tinyurl.com", ".t.co", ".goo.gl", ".ow.ly",
+	".is.gd", ".v.gd", ".buff.ly", ".rebrand.ly", ".cutt.ly", ".shorturl.at",
+	".tiny.cc", ".rb.gy", ".t.ly", ".s.id", ".lnkd.in", ".clck.ru",
+}
+
+// maxChainHops сколько сокращателей подряд раскрываем, прежде чем отказать
+const maxChainHops = 5
+
+// newChainClient копирует клиент и отключает в нем переход по редиректам:
+// каждый шаг цепочки проверяется до запроса к нему
+func newChainClient(client *http.Client) *http.Client {
+	if client == nil {
+		return nil
+	}
+
+	chain := *client
+	chain.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &chain
+}
+
+// resolveDestination проверяет, что цель не ведет на этот сервис, и раскрывает
+// ссылки других сокращателей до конечного адреса. Без клиента цепочки
+// ссылки сокращателей отклоняются
+func (s *URLService) resolveDestination(ctx context.Context, rawURL string) (string, error) {
+	current := rawURL
+
+	for hop := 0; ; hop++ {
+		u, err := url.Parse(current)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+		}
+
+		host := u.Hostname()
+		if s.ownHosts.Contains(host) {
+			return "", fmt.Errorf("%w: %s", ErrSelfReferential, host)
+		}
+		if !s.shorteners.Contains(host) {
+			return current, nil
+		}
+
+		if s.chainClient == nil {
+			return "", fmt.Errorf("%w: %s", ErrShortenerChain, host)
+		}
+		if hop == maxChainHops {
+			return "", fmt.Errorf("%w: more than %d redirects", ErrShortenerChain, maxChainHops)
+		}
+
+		next, err := s.nextHop(ctx, u)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %v", ErrShortenerChain, host, err)
+		}
+
+		// Следующий шаг проверяется так же, как исходный URL, — до запроса к нему
+		current = s.validator.NormalizeURL(next)
+		if err := s.validator.ValidateURL(ctx, current); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+		}
+	}
+}
+
+// nextHop запрашивает ссылку сокращателя и возвращает адрес из Location
+func (s *URLService) nextHop(ctx context.Context, u *url.URL) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.chainClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return "", fmt.Errorf("no redirect (status %d)", resp.StatusCode)
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", err
+	}
+
+	return location.String(), nil
+}
+
+// newOwnHosts собирает хосты самого сервиса: хост BaseURL и дополнительные домены
+func newOwnHosts(baseURL string, domains []string) (*validator.DomainSet, error) {
+	hosts := append([]string(nil), domains...)
+	if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, u.Hostname())
+	}
+
+	return validator.NewDomainSet(hosts...)
+}
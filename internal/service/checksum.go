@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxCodeCorrections сколько исправлений опечатки предлагать
+const maxCodeCorrections = 3
+
+// VerifyCode проверяет контрольный символ кода до обращения к хранилищу:
+// код вида сгенерированного с неверным символом — опечатка, искать его незачем
+// Коды другого вида (кастомные с разделителями, короче базовой длины) не проверяются.
+// С CodeChecksumLegacy такие коды могут быть старыми, и проверка всегда проходит —
+// опечатку объясняет LooksMistyped после промаха в хранилище
+func (s *URLService) VerifyCode(code string) error {
+	if s.checksumLegacy || !s.LooksMistyped(code) {
+		return nil
+	}
+	return ErrCodeMistyped
+}
+
+// LooksMistyped сообщает, что у кода вида сгенерированного не сходится контрольный символ
+func (s *URLService) LooksMistyped(code string) bool {
+	return s.checksumApplies(code) && !s.checksum.VerifyChecksum(code)
+}
+
+// SuggestCorrections возвращает существующие коды, отличающиеся от code
+// одним символом или перестановкой соседних — для подсказки «возможно, вы имели в виду»
+func (s *URLService) SuggestCorrections(ctx context.Context, code string) ([]string, error) {
+	if !s.checksumApplies(code) {
+		return nil, nil
+	}
+
+	candidates := s.checksum.Corrections(code)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	taken, err := s.takenCodes(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	var corrections []string
+	for _, candidate := range candidates {
+		if !taken[candidate] {
+			continue
+		}
+		corrections = append(corrections, candidate)
+		if len(corrections) == maxCodeCorrections {
+			break
+		}
+	}
+
+	return corrections, nil
+}
+
+// validateCustomCode проверяет кастомный код
+// Без CodeChecksumLegacy код вида сгенерированного обязан иметь верный
+// контрольный символ: иначе VerifyCode отверг бы его до поиска в хранилище
+func (s *URLService) validateCustomCode(code string) error {
+	if err := s.validator.ValidateCustomCode(code); err != nil {
+		return err
+	}
+
+	if err := s.VerifyCode(s.validator.NormalizeCode(code)); err != nil {
+		return fmt.Errorf("custom code looks generated but has no valid check character: %w", err)
+	}
+
+	return nil
+}
+
+// checksumApplies сообщает, может ли код быть сгенерированным с контрольным символом:
+// не короче базовой длины и только из символов алфавита
+func (s *URLService) checksumApplies(code string) bool {
+	return s.checksum != nil &&
+		len(code) >= s.checksum.Length() &&
+		s.checksum.ValidateCode(code)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/storage"
+)
+
+const (
+	// poolCheckInterval как часто воркер проверяет пул без явного сигнала
+	poolCheckInterval = 5 * time.Second
+
+	// defaultReservationTTL срок резерва по умолчанию
+	defaultReservationTTL = 24 * time.Hour
+
+	// poolReclaimInterval как часто пополнение снимает просроченный резерв
+	poolReclaimInterval = time.Minute
+)
+
+// codePool пул заранее сгенерированных и зарезервированных в хранилище кодов
+//
+// ShortenURL берет код из пула без проверки уникальности; фоновый воркер
+// пополняет пул, когда он опускается ниже lowWatermark. Окончательным арбитром
+// остается уникальный индекс: код, занятый кастомной ссылкой, отбрасывается
+//
+// Резерв живет не дольше ttl: пополнение снимает чужой просроченный резерв
+// упавших экземпляров, а свои коды старше ttl пул не выдает, а возвращает
+type codePool struct {
+	reserver     storage.CodeReserver
+	generate     func() (string, error)
+	logger       *slog.Logger
+	size         int
+	lowWatermark int
+	ttl          time.Duration
+
+	codes  chan pooledCode
+	refill chan struct{}
+
+	// Выданные коды, резерв с которых снимается при следующем пополнении
+	usedMu sync.Mutex
+	used   []string
+
+	// Время последней очистки просроченного резерва; трогает только воркер
+	lastReclaim time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// pooledCode код пула и время его резерва
+type pooledCode struct {
+	code       string
+	reservedAt time.Time
+}
+
+func newCodePool(reserver storage.CodeReserver, generate func() (string, error), size, lowWatermark int, ttl time.Duration, logger *slog.Logger) *codePool {
+	if lowWatermark <= 0 || lowWatermark >= size {
+		lowWatermark = size / 4
+	}
+	if ttl <= 0 {
+		ttl = defaultReservationTTL
+	}
+
+	return &codePool{
+		reserver:     reserver,
+		generate:     generate,
+		logger:       logger,
+		size:         size,
+		lowWatermark: lowWatermark,
+		ttl:          ttl,
+		codes:        make(chan pooledCode, size),
+		refill:       make(chan struct{}, 1),
+	}
+}
+
+// start запускает фоновое пополнение
+func (p *codePool) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go p.run(ctx)
+}
+
+// take возвращает код из пула; false — пул пуст
+// Коды с истекшим резервом могли уже достаться другому экземпляру — их пропускаем
+func (p *codePool) take() (string, bool) {
+	expired := time.Now().Add(-p.ttl)
+
+	for {
+		select {
+		case c := <-p.codes:
+			if c.reservedAt.Before(expired) {
+				p.done(c.code)
+				continue
+			}
+			if len(p.codes) < p.lowWatermark {
+				p.signal()
+			}
+			return c.code, true
+		default:
+			p.signal()
+			return "", false
+		}
+	}
+}
+
+// done отмечает код как выданный или отброшенный — его резерв больше не нужен
+func (p *codePool) done(code string) {
+	p.usedMu.Lock()
+	p.used = append(p.used, code)
+	p.usedMu.Unlock()
+}
+
+// available количество кодов в пуле
+func (p *codePool) available() int {
+	return len(p.codes)
+}
+
+// close останавливает воркер и снимает резерв со всех невыданных кодов
+func (p *codePool) close(ctx context.Context) error {
+	p.cancel()
+	p.wg.Wait()
+
+	release := p.takeUsed()
+	for len(p.codes) > 0 {
+		release = append(release, (<-p.codes).code)
+	}
+
+	if len(release) == 0 {
+		return nil
+	}
+
+	return p.reserver.ReleaseCodes(ctx, release)
+}
+
+func (p *codePool) signal() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+func (p *codePool) run(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(poolCheckInterval)
+	defer ticker.Stop()
+
+	p.fill(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.refill:
+		case <-ticker.C:
+		}
+
+		if len(p.codes) < p.lowWatermark {
+			p.fill(ctx)
+		}
+	}
+}
+
+// fill снимает резерв с выданных кодов, не чаще poolReclaimInterval снимает
+// просроченный резерв и доводит пул до полного размера
+func (p *codePool) fill(ctx context.Context) {
+	if used := p.takeUsed(); len(used) > 0 {
+		if err := p.reserver.ReleaseCodes(ctx, used); err != nil {
+			p.logger.Warn("failed to release used codes", "error", err)
+			p.requeueUsed(used)
+		}
+	}
+
+	if now := time.Now(); now.Sub(p.lastReclaim) >= poolReclaimInterval {
+		p.lastReclaim = now
+		p.reclaim(ctx, now)
+	}
+
+	need := p.size - len(p.codes)
+	if need <= 0 {
+		return
+	}
+
+	candidates := make([]string, 0, need)
+	seen := make(map[string]struct{}, need)
+	for len(candidates) < need {
+		code, err := p.generate()
+		if err != nil {
+			p.logger.Error("failed to generate pool code", "error", err)
+			return
+		}
+		if _, dup := seen[code]; dup {
+			continue
+		}
+		seen[code] = struct{}{}
+		candidates = append(candidates, code)
+	}
+
+	reservedAt := time.Now()
+	reserved, err := p.reserver.ReserveCodes(ctx, candidates)
+	if err != nil {
+		p.logger.Error("failed to reserve pool codes", "error", err)
+		return
+	}
+
+	for _, code := range reserved {
+		select {
+		case p.codes <- pooledCode{code: code, reservedAt: reservedAt}:
+		default:
+			// Пул успел заполниться — лишний резерв вернем при следующем пополнении
+			p.done(code)
+		}
+	}
+
+	p.logger.Debug("code pool refilled",
+		"reserved", len(reserved),
+		"requested", len(candidates),
+		"available", len(p.codes),
+	)
+}
+
+// reclaim снимает резерв старше ttl, оставленный упавшими экземплярами
+func (p *codePool) reclaim(ctx context.Context, now time.Time) {
+	reclaimed, err := p.reserver.ReclaimCodes(ctx, now.Add(-p.ttl))
+	if err != nil {
+		p.logger.Warn("failed to reclaim stale reservations", "error", err)
+		return
+	}
+
+	if reclaimed > 0 {
+		p.logger.Info("reclaimed stale code reservations", "count", reclaimed)
+	}
+}
+
+func (p *codePool) takeUsed() []string {
+	p.usedMu.Lock()
+	defer p.usedMu.Unlock()
+
+	used := p.used
+	p.used = nil
+	return used
+}
+
+func (p *codePool) requeueUsed(codes []string) {
+	p.usedMu.Lock()
+	defer p.usedMu.Unlock()
+
+	p.used = append(p.used, codes...)
+}
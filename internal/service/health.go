@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+	"github.com/dmitrycr/ShortUrl/internal/storage"
+	"github.com/dmitrycr/ShortUrl/internal/validator"
+)
+
+const (
+	// healthSweepInterval как часто чекер ищет ссылки, которые пора проверить
+	healthSweepInterval = time.Minute
+
+	// healthBatchSize сколько ссылок берется из хранилища за раз
+	healthBatchSize = 100
+
+	// maxHealthRedirects сколько редиректов проходит проверка
+	maxHealthRedirects = 5
+
+	// defaultHealthTimeout таймаут запроса, если клиент не задан
+	defaultHealthTimeout = 10 * time.Second
+
+	// defaultHealthConcurrency одновременных проверок, если не задано
+	defaultHealthConcurrency = 4
+
+	// healthUserAgent представляется сайтам, чтобы проверку можно было опознать
+	healthUserAgent = "ShortUrl-HealthCheck/1.0"
+
+	// maxHealthListLimit сколько ссылок максимум отдает отчет
+	maxHealthListLimit = 1000
+)
+
+// healthChecker периодически проверяет целевые URL и сохраняет статус ответа
+//
+// Сначала отправляется HEAD, при ошибке или статусе 4xx/5xx — GET: многие сайты
+// не поддерживают HEAD. Запросы к одному хосту разнесены не менее чем на hostDelay
+type healthChecker struct {
+	store       storage.HealthTracker
+	validator   *validator.URLValidator
+	client      *http.Client
+	interval    time.Duration
+	concurrency int
+	hosts       *hostLimiter
+	logger      *slog.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newHealthChecker(store storage.HealthTracker, v *validator.URLValidator, client *http.Client, interval time.Duration, concurrency int, hostDelay time.Duration, logger *slog.Logger) *healthChecker {
+	if client == nil {
+		client = &http.Client{Timeout: defaultHealthTimeout, Transport: validator.NewSafeTransport()}
+	}
+	if concurrency <= 0 {
+		concurrency = defaultHealthConcurrency
+	}
+
+	c := &healthChecker{
+		store:       store,
+		validator:   v,
+		interval:    interval,
+		concurrency: concurrency,
+		hosts:       newHostLimiter(hostDelay),
+		logger:      logger,
+	}
+
+	// Каждый шаг редиректа проверяется, как целевой URL при создании ссылки;
+	// адрес подключения проверяет транспорт — DNS мог измениться после создания
+	checked := *client
+	checked.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxHealthRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxHealthRedirects)
+		}
+		return c.validator.ValidateURL(req.Context(), req.URL.String())
+	}
+	c.client = &checked
+
+	return c
+}
+
+// start запускает фоновые проверки
+func (c *healthChecker) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go c.run(ctx)
+}
+
+// close останавливает проверки и дожидается текущих запросов
+func (c *healthChecker) close() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+func (c *healthChecker) run(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(min(c.interval, healthSweepInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checked, broken, err := c.sweep(ctx)
+		if err != nil && ctx.Err() == nil {
+			c.logger.Error("link health check failed", "error", err)
+		}
+		if checked > 0 {
+			c.logger.Info("link health checked", "checked", checked, "broken", broken)
+		}
+	}
+}
+
+// sweep проверяет все ссылки, не проверявшиеся дольше interval
+func (c *healthChecker) sweep(ctx context.Context) (checked, broken int, err error) {
+	checkedBefore := time.Now().Add(-c.interval)
+
+	for ctx.Err() == nil {
+		urls, err := c.store.DueHealthChecks(ctx, checkedBefore, healthBatchSize)
+		if err != nil {
+			return checked, broken, err
+		}
+
+		var (
+			mu        sync.Mutex
+			recordErr error
+			wg        sync.WaitGroup
+		)
+		sem := make(chan struct{}, c.concurrency)
+
+		for _, u := range urls {
+			sem <- struct{}{}
+			wg.Add(1)
+
+			go func(u model.URL) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				status := c.probe(ctx, u.OriginalURL)
+				if ctx.Err() != nil {
+					return
+				}
+				now := time.Now()
+				err := c.store.RecordHealth(ctx, u.ShortCode, status, now)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil && !errors.Is(err, storage.ErrNotFound) {
+					recordErr = err
+					return
+				}
+				checked++
+				if model.HealthOf(status, &now) == model.HealthBroken {
+					broken++
+				}
+			}(u)
+		}
+		wg.Wait()
+
+		// Без записи результата те же ссылки вернулись бы снова
+		if recordErr != nil {
+			return checked, broken, recordErr
+		}
+		if len(urls) < healthBatchSize {
+			break
+		}
+	}
+
+	return checked, broken, nil
+}
+
+// probe возвращает статус ответа целевого URL; 0 — адрес недоступен
+func (c *healthChecker) probe(ctx context.Context, rawURL string) int {
+	// Имя хоста могло начать указывать во внутреннюю сеть
+	if err := c.validator.ValidateURL(ctx, rawURL); err != nil {
+		return 0
+	}
+
+	status, err := c.request(ctx, http.MethodHead, rawURL)
+	if err != nil || status >= 400 {
+		status, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+	if err != nil {
+		return 0
+	}
+
+	return status
+}
+
+func (c *healthChecker) request(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", healthUserAgent)
+
+	if err := c.hosts.wait(ctx, req.URL.Hostname()); err != nil {
+		return 0, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// hostLimiter разносит запросы к одному хосту не менее чем на delay
+type hostLimiter struct {
+	delay time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time // хост → время, раньше которого запрос не отправляется
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+	return &hostLimiter{delay: delay, next: make(map[string]time.Time)}
+}
+
+// wait занимает ближайшее свободное окно хоста и ждет его
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	if l.delay <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.delay)
+
+	// Удаляем прошедшие окна, чтобы карта не росла
+	for h, t := range l.next {
+		if t.Before(now) {
+			delete(l.next, h)
+		}
+	}
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ListURLsByHealth возвращает ссылки в состоянии health
+// Отчет доступен без авторизации, поэтому отдает только битые ссылки
+// (model.HealthBroken), никогда — ссылки с паролем или лимитом переходов,
+// и без адресов назначения: в них бывают токены и другие приватные данные
+func (s *URLService) ListURLsByHealth(ctx context.Context, health string, limit int) ([]model.Stats, error) {
+	if health != model.HealthBroken {
+		return nil, fmt.Errorf("%w: only %q links can be listed", ErrInvalidHealth, model.HealthBroken)
+	}
+
+	tracker, ok := s.storage.(storage.HealthTracker)
+	if !ok {
+		return nil, ErrHealthDisabled
+	}
+
+	if limit <= 0 || limit > maxHealthListLimit {
+		limit = maxHealthListLimit
+	}
+
+	list, err := tracker.ListByHealth(ctx, health, limit)
+	if err != nil {
+		if errors.Is(err, storage.ErrUnknownHealth) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidHealth, err)
+		}
+		return nil, fmt.Errorf("failed to list urls: %w", err)
+	}
+
+	for i := range list {
+		list[i].Health = model.HealthOf(list[i].LastStatus, list[i].LastCheckedAt)
+		list[i].OriginalURL = ""
+	}
+	return list, nil
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/storage"
+	"github.com/dmitrycr/ShortUrl/internal/validator"
+	"github.com/dmitrycr/ShortUrl/pkg/generator"
+)
+
+const (
+	// collisionRateAlpha вес нового наблюдения в скользящей доле коллизий
+	collisionRateAlpha = 0.02
+
+	// minCollisionSamples сколько вставок нужно, прежде чем доверять доле коллизий
+	minCollisionSamples = 50
+
+	// keyspaceSyncInterval как часто длина сверяется с числом кодов в хранилище
+	keyspaceSyncInterval = 10 * time.Minute
+)
+
+// keyspace следит за заполнением пространства случайных кодов
+//
+// Доля коллизий при вставке случайного кода примерно равна доле занятых
+// кодов текущей длины. Когда она превышает порог, длина увеличивается
+// на единицу — пространство растет в размер алфавита раз
+//
+// Если хранилище умеет считать коды по длине (storage.CodeCounter), занятость
+// считается по нему: при старте и раз в keyspaceSyncInterval длина выводится
+// из числа сохраненных кодов — она переживает перезапуск и одинакова на всех
+// экземплярах. Доля коллизий остается запасным сигналом между сверками
+//
+// Словесные коды (StrategyWords) растут только через конфигурацию:
+// их длина не выражается числом символов
+type keyspace struct {
+	mu        sync.RWMutex
+	gen       generator.CodeGenerator
+	opts      []generator.Option
+	length    int     // 0 — длина кода не фиксирована (словесные коды)
+	threshold float64 // 0 — длина фиксирована
+	rate      float64 // экспоненциальное среднее доли коллизий
+	samples   int
+
+	// stored кодов текущей длины в хранилище: последний подсчет плюс свои
+	// вставки; -1 — хранилище не считает коды
+	stored int64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newKeyspace(length int, threshold float64, opts []generator.Option) *keyspace {
+	return &keyspace{
+		gen:       generator.NewGenerator(length, opts...),
+		opts:      opts,
+		length:    length,
+		threshold: threshold,
+		stored:    -1,
+	}
+}
+
+// newWordKeyspace пространство словесных кодов фиксированного размера
+func newWordKeyspace(gen *generator.WordGenerator) *keyspace {
+	return &keyspace{gen: gen, stored: -1}
+}
+
+// generate возвращает случайный код текущей длины
+func (k *keyspace) generate() (string, error) {
+	k.mu.RLock()
+	gen := k.gen
+	k.mu.RUnlock()
+
+	return gen.Generate()
+}
+
+// adaptive сообщает, может ли длина расти
+func (k *keyspace) adaptive() bool {
+	return k.threshold > 0
+}
+
+// observe учитывает результат вставки кода длины length
+// Возвращает true, если после этого длина была увеличена
+func (k *keyspace) observe(length int, collision bool) bool {
+	if !k.adaptive() {
+		return false
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// Результаты для кодов прежней длины (например, из пула) уже не показательны
+	if length != k.length {
+		return false
+	}
+
+	hit := 0.0
+	if collision {
+		hit = 1
+	}
+	k.rate += collisionRateAlpha * (hit - k.rate)
+	k.samples++
+
+	if !collision && k.stored >= 0 {
+		k.stored++
+		if k.utilizationLocked() > k.threshold {
+			return k.growLocked()
+		}
+	}
+
+	if k.samples >= minCollisionSamples && k.rate > k.threshold {
+		return k.growLocked()
+	}
+	return false
+}
+
+// sync выводит длину из числа сохраненных кодов каждой длины: наименьшая
+// длина не меньше текущей, занятость которой не выше порога
+// Возвращает true, если длина была увеличена
+func (k *keyspace) sync(counts map[int]int64) bool {
+	if !k.adaptive() {
+		return false
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	grown := false
+	for {
+		k.stored = counts[k.length]
+		if k.utilizationLocked() <= k.threshold || !k.growLocked() {
+			return grown
+		}
+		grown = true
+	}
+}
+
+// startSync сверяет длину с хранилищем сразу и затем периодически
+func (k *keyspace) startSync(counter storage.CodeCounter, logger *slog.Logger) {
+	ctx, cancel := context.WithCancel(context.Background())
+	k.cancel = cancel
+
+	k.syncWith(ctx, counter, logger)
+
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+
+		ticker := time.NewTicker(keyspaceSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				k.syncWith(ctx, counter, logger)
+			}
+		}
+	}()
+}
+
+// close останавливает периодическую сверку
+func (k *keyspace) close() {
+	if k.cancel == nil {
+		return
+	}
+	k.cancel()
+	k.wg.Wait()
+}
+
+func (k *keyspace) syncWith(ctx context.Context, counter storage.CodeCounter, logger *slog.Logger) {
+	counts, err := counter.CountCodesByLength(ctx)
+	if err != nil {
+		logger.Warn("failed to count stored codes", "error", err)
+		return
+	}
+
+	if k.sync(counts) {
+		length, _, size := k.state()
+		logger.Warn("short code length increased",
+			"length", length,
+			"keyspace", size,
+			"reason", "stored codes above threshold",
+		)
+	}
+}
+
+// grow увеличивает длину кода; false — достигнут максимум
+func (k *keyspace) grow() bool {
+	if !k.adaptive() {
+		return false
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.growLocked()
+}
+
+func (k *keyspace) growLocked() bool {
+	if k.length >= validator.MaxCodeLength {
+		return false
+	}
+
+	k.length++
+	k.gen = generator.NewGenerator(k.length, k.opts...)
+	k.rate = 0
+	k.samples = 0
+	if k.stored > 0 {
+		// Кодов новой длины еще нет; точное число даст следующая сверка
+		k.stored = 0
+	}
+
+	return true
+}
+
+// utilizationLocked доля занятых кодов текущей длины по данным хранилища;
+// -1 — хранилище не считает коды
+func (k *keyspace) utilizationLocked() float64 {
+	if k.stored < 0 {
+		return -1
+	}
+	return float64(k.stored) / k.gen.Capacity()
+}
+
+// state возвращает текущую длину, долю коллизий и размер пространства кодов
+func (k *keyspace) state() (length int, rate float64, size float64) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.length, k.rate, k.gen.Capacity()
+}
+
+// utilization возвращает долю занятых кодов текущей длины; -1 — неизвестна
+func (k *keyspace) utilization() float64 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.utilizationLocked()
+}
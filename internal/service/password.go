@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+)
+
+// Хеш паролей ссылок: PBKDF2-HMAC-SHA256 в формате
+// pbkdf2-sha256$<итерации>$<соль>$<ключ> (base64 без выравнивания)
+const (
+	passwordHashScheme = "pbkdf2-sha256"
+	passwordSaltLength = 16
+	passwordKeyLength  = 32
+
+	// maxPasswordLength предел длины пароля в символах
+	maxPasswordLength = 128
+)
+
+// passwordIterations число итераций PBKDF2 для новых хешей (рекомендация OWASP)
+// Хранится в хеше, поэтому изменение не ломает старые пароли
+var passwordIterations = 600_000
+
+// Ограничение перебора паролей
+const (
+	// passwordFreeAttempts попыток одного клиента к одной ссылке без задержки
+	passwordFreeAttempts = 5
+
+	// passwordClientFreeAttempts попыток одного клиента ко всем ссылкам без задержки:
+	// перебор по многим кодам тоже упирается в лимит
+	passwordClientFreeAttempts = 30
+
+	// passwordCodeFreeAttempts попыток всех клиентов к одной ссылке без задержки:
+	// перебор с многих адресов. Порог высокий, чтобы чужие ошибки редко
+	// задерживали настоящих пользователей
+	passwordCodeFreeAttempts = 100
+
+	// passwordBaseLockout блокировка после первой лишней попытки; дальше удваивается
+	passwordBaseLockout = time.Second
+
+	// passwordMaxLockout предел блокировки
+	passwordMaxLockout = 15 * time.Minute
+
+	// passwordAttemptsTTL через сколько без попыток счетчик забывается
+	passwordAttemptsTTL = time.Hour
+)
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// hashPassword хеширует пароль в слоте проверки паролей: создание ссылок
+// с паролем не должно занимать больше ядер, чем проверка
+func (s *URLService) hashPassword(ctx context.Context, password string) (string, error) {
+	release, err := s.acquirePasswordSlot(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	return hashPassword(password)
+}
+
+// acquirePasswordSlot ждет свободный слот для PBKDF2, пока не отменен ctx
+func (s *URLService) acquirePasswordSlot(ctx context.Context) (release func(), err error) {
+	select {
+	case s.passwordSlots <- struct{}{}:
+		return func() { <-s.passwordSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// hashPassword возвращает хеш пароля со случайной солью
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s$%d$%s$%s",
+		passwordHashScheme,
+		passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// checkPasswordHash сравнивает пароль с хешем за постоянное время
+func checkPasswordHash(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != passwordHashScheme {
+		return false, errMalformedHash
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, errMalformedHash
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(want) == 0 {
+		return false, errMalformedHash
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(key, want) == 1, nil
+}
+
+// CheckPassword проверяет пароль защищенной ссылки; для ссылок без пароля — nil
+// client — адрес клиента. Попытки считаются по паре клиент+ссылка, по клиенту
+// в целом и по ссылке в целом с высоким порогом: перебор с одного адреса
+// блокирует только этот адрес, а перебор с многих — упирается в лимит ссылки
+//
+// Каждая попытка учитывается до проверки, поэтому параллельные запросы
+// не обходят ограничение. Одновременно вычисляется не больше хешей, чем
+// есть процессоров: остальные ждут очереди, пока не отменен ctx
+func (s *URLService) CheckPassword(ctx context.Context, url *model.URL, password, client string) error {
+	if url.PasswordHash == "" {
+		return nil
+	}
+	if password == "" {
+		return ErrPasswordRequired
+	}
+
+	clientKey := "client:" + client
+	codeKey := "code:" + url.ShortCode
+	pairKey := "pair:" + client + "|" + url.ShortCode
+
+	for _, limit := range []struct {
+		key  string
+		free int
+	}{
+		{clientKey, passwordClientFreeAttempts},
+		{codeKey, passwordCodeFreeAttempts},
+		{pairKey, passwordFreeAttempts},
+	} {
+		if retryAfter := s.passwords.attempt(limit.key, limit.free); retryAfter > 0 {
+			return fmt.Errorf("%w: retry in %s", ErrTooManyAttempts, retryAfter.Round(time.Second))
+		}
+	}
+
+	release, err := s.acquirePasswordSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ok, err := checkPasswordHash(url.PasswordHash, password)
+	if err != nil {
+		return fmt.Errorf("failed to check password: %w", err)
+	}
+	if !ok {
+		return ErrWrongPassword
+	}
+
+	// Верный пароль не тратит общие лимиты клиента и ссылки и сбрасывает счетчик пары
+	s.passwords.refund(clientKey)
+	s.passwords.refund(codeKey)
+	s.passwords.reset(pairKey)
+	return nil
+}
+
+// redactProtected скрывает адрес назначения защищенной ссылки:
+// статистика доступна без пароля
+func redactProtected(stats *model.Stats) {
+	if stats.PasswordProtected {
+		stats.OriginalURL = ""
+	}
+}
+
+// passwordThrottle ограничивает перебор паролей по ключу (клиент, ссылка или
+// клиент+ссылка): после бесплатных попыток каждая следующая возможна
+// не раньше, чем через удваивающуюся задержку
+type passwordThrottle struct {
+	mu        sync.Mutex
+	attempts  map[string]*passwordAttempts
+	lastSweep time.Time
+}
+
+type passwordAttempts struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+func newPasswordThrottle() *passwordThrottle {
+	return &passwordThrottle{attempts: make(map[string]*passwordAttempts)}
+}
+
+// attempt учитывает попытку; если ключ заблокирован, возвращает оставшееся время
+func (t *passwordThrottle) attempt(key string, free int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.sweep(now)
+
+	a, ok := t.attempts[key]
+	if !ok {
+		a = &passwordAttempts{}
+		t.attempts[key] = a
+	}
+	if now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now)
+	}
+
+	a.count++
+	a.last = now
+	if extra := a.count - free; extra > 0 {
+		lockout := passwordMaxLockout
+		if extra < 32 {
+			lockout = min(passwordBaseLockout<<(extra-1), passwordMaxLockout)
+		}
+		a.lockedUntil = now.Add(lockout)
+	}
+
+	return 0
+}
+
+// reset забывает попытки после верного пароля
+func (t *passwordThrottle) reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.attempts, key)
+}
+
+// refund возвращает одну попытку: удачная не должна приближать блокировку.
+// Полный сброс позволил бы обнулять общий лимит одним известным паролем
+func (t *passwordThrottle) refund(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if a, ok := t.attempts[key]; ok && a.count > 0 {
+		a.count--
+	}
+}
+
+// sweep раз в passwordAttemptsTTL удаляет давно не использованные счетчики,
+// чтобы карта не росла
+func (t *passwordThrottle) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < passwordAttemptsTTL {
+		return
+	}
+	t.lastSweep = now
+
+	for key, a := range t.attempts {
+		if now.Sub(a.last) > passwordAttemptsTTL && now.After(a.lockedUntil) {
+			delete(t.attempts, key)
+		}
+	}
+}
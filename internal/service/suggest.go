@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+	"github.com/dmitrycr/ShortUrl/internal/storage"
+	"github.com/dmitrycr/ShortUrl/internal/validator"
+)
+
+// maxCodeSuggestions сколько свободных альтернатив предлагать для занятого кода
+const maxCodeSuggestions = 5
+
+// Причины, по которым кастомный код недоступен
+const (
+	ReasonInvalid  = "invalid"
+	ReasonReserved = "reserved"
+	ReasonBlocked  = "blocked"
+	ReasonTaken    = "taken"
+)
+
+var (
+	// Приставки и окончания для словесных вариаций кода
+	suggestionPrefixes = []string{"my", "get", "go"}
+	suggestionSuffixes = []string{"app", "hq", "link"}
+
+	// Разделители между кодом и числовым суффиксом ("" — без разделителя)
+	suggestionSeparators = []string{"", "-", "_"}
+)
+
+// maxSuggestionNumber до какого числа перебираются числовые суффиксы
+const maxSuggestionNumber = 20
+
+// CheckCodeAvailability проверяет, можно ли занять кастомный код
+// Результат не резервирует код: между проверкой и созданием его могут занять
+func (s *URLService) CheckCodeAvailability(ctx context.Context, code string) (*model.CodeAvailability, error) {
+	code = s.validator.NormalizeCode(code)
+	result := &model.CodeAvailability{Code: code}
+
+	if err := s.validateCustomCode(code); err != nil {
+		switch {
+		case errors.Is(err, validator.ErrReservedCode):
+			result.Reason = ReasonReserved
+		case errors.Is(err, validator.ErrBlockedCode):
+			result.Reason = ReasonBlocked
+			return result, nil
+		default:
+			result.Reason = ReasonInvalid
+			return result, nil
+		}
+	} else {
+		taken, err := s.takenCodes(ctx, []string{code})
+		if err != nil {
+			return nil, err
+		}
+		if !taken[code] {
+			result.Available = true
+			return result, nil
+		}
+		result.Reason = ReasonTaken
+	}
+
+	suggestions, err := s.SuggestCodes(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	result.Suggestions = suggestions
+
+	return result, nil
+}
+
+// SuggestCodes возвращает свободные коды, похожие на code
+// Кандидаты проходят ту же валидацию, что и кастомные коды, и проверяются
+// в хранилище одним запросом
+func (s *URLService) SuggestCodes(ctx context.Context, code string) ([]string, error) {
+	code = s.validator.NormalizeCode(code)
+	candidates := s.codeVariants(code)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	taken, err := s.takenCodes(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]string, 0, maxCodeSuggestions)
+	for _, candidate := range candidates {
+		if taken[candidate] {
+			continue
+		}
+		suggestions = append(suggestions, candidate)
+		if len(suggestions) == maxCodeSuggestions {
+			break
+		}
+	}
+
+	return suggestions, nil
+}
+
+// codeVariants строит кандидатов в порядке предпочтения:
+// code2, code-2, code_2, затем словесные вариации, затем остальные числа
+func (s *URLService) codeVariants(code string) []string {
+	base := strings.TrimRight(code, "-_")
+	if base == "" {
+		return nil
+	}
+
+	seen := map[string]struct{}{code: {}}
+	var variants []string
+
+	add := func(prefix, suffix string) {
+		// Обрезаем основу, чтобы вариант уложился в максимальную длину кода
+		stem := base
+		if room := validator.MaxCodeLength - len(prefix) - len(suffix); len(stem) > room {
+			stem = stem[:room]
+		}
+
+		variant := prefix + stem + suffix
+		if _, dup := seen[variant]; dup {
+			return
+		}
+		seen[variant] = struct{}{}
+
+		if s.validateCustomCode(variant) != nil {
+			return
+		}
+		variants = append(variants, variant)
+	}
+
+	addNumber := func(n int) {
+		for _, sep := range suggestionSeparators {
+			add("", sep+strconv.Itoa(n))
+		}
+	}
+
+	addNumber(2)
+	for i := range suggestionPrefixes {
+		add(suggestionPrefixes[i]+"-", "")
+		add("", "-"+suggestionSuffixes[i])
+	}
+	for n := 3; n <= maxSuggestionNumber; n++ {
+		addNumber(n)
+	}
+
+	return variants
+}
+
+// takenCodes возвращает множество занятых кодов из списка (ключи нормализованы)
+func (s *URLService) takenCodes(ctx context.Context, codes []string) (map[string]bool, error) {
+	taken := make(map[string]bool, len(codes))
+
+	if checker, ok := s.storage.(storage.CodeChecker); ok {
+		list, err := checker.TakenCodes(ctx, codes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check codes: %w", err)
+		}
+		for _, code := range list {
+			taken[s.validator.NormalizeCode(code)] = true
+		}
+		return taken, nil
+	}
+
+	// Хранилище без пакетной проверки — по запросу на код
+	for _, code := range codes {
+		_, err := s.storage.GetByShortCode(ctx, code)
+		switch {
+		case err == nil, errors.Is(err, storage.ErrExpired):
+			taken[s.validator.NormalizeCode(code)] = true
+		case errors.Is(err, storage.ErrNotFound):
+		default:
+			return nil, fmt.Errorf("failed to check code: %w", err)
+		}
+	}
+
+	return taken, nil
+}
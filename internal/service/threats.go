@@ -0,0 +1,20 @@
+package service
+
+import (
+	"github.com/dmitrycr/ShortUrl/pkg/threatlist"
+)
+
+// ThreatChecker проверяет целевой URL по спискам вредоносных адресов
+// Реализуется threatlist.List и threatlist.Feed
+type ThreatChecker interface {
+	Check(rawURL string) (threatlist.Match, bool)
+}
+
+// CheckThreat ищет целевой URL в списках вредоносных адресов
+// Списки обновляются, поэтому ссылка может стать опасной уже после создания
+func (s *URLService) CheckThreat(originalURL string) (threatlist.Match, bool) {
+	if s.threats == nil {
+		return threatlist.Match{}, false
+	}
+	return s.threats.Check(originalURL)
+}
@@ -4,71 +4,454 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/dmitrycr/ShortUrl/internal/model"
 	"github.com/dmitrycr/ShortUrl/internal/storage"
 	"github.com/dmitrycr/ShortUrl/internal/validator"
+	"github.com/dmitrycr/ShortUrl/pkg/blocklist"
 	"github.com/dmitrycr/ShortUrl/pkg/generator"
 )
 
 var (
-	ErrURLNotFound     = errors.New("url not found")
-	ErrURLExpired      = errors.New("url has expired")
-	ErrInvalidURL      = errors.New("invalid url")
-	ErrCodeAlreadyUsed = errors.New("short code already in use")
+	ErrURLNotFound        = errors.New("url not found")
+	ErrURLExpired         = errors.New("url has expired")
+	ErrInvalidURL         = errors.New("invalid url")
+	ErrDomainForbidden    = errors.New("destination domain is not allowed")
+	ErrURLMalicious       = errors.New("destination url is flagged as malicious")
+	ErrSelfReferential    = errors.New("destination points to this shortener")
+	ErrShortenerChain     = errors.New("destination is another url shortener")
+	ErrInvalidHealth      = errors.New("invalid health filter")
+	ErrInvalidRedirect    = errors.New("redirect type must be 301, 302, 307 or 308")
+	ErrInvalidPassword    = errors.New("password must be at most 128 characters")
+	ErrPasswordRequired   = errors.New("password required")
+	ErrWrongPassword      = errors.New("wrong password")
+	ErrTooManyAttempts    = errors.New("too many password attempts")
+	ErrInvalidMaxClicks   = errors.New("max clicks must not be negative")
+	ErrClicksExhausted    = errors.New("url has reached its click limit")
+	ErrClickLimitDisabled = errors.New("storage does not support click limits")
+	ErrHealthDisabled     = errors.New("storage does not track link health")
+	ErrCodeAlreadyUsed    = errors.New("short code already in use")
+	ErrCodeExhausted      = errors.New("failed to allocate unique short code")
+	ErrUnknownStrategy    = errors.New("unknown code strategy")
+	ErrInvalidCode        = errors.New("invalid custom code")
+	ErrCodeBlocked        = errors.New("custom code contains a blocked word")
+	ErrCodeReserved       = errors.New("custom code is reserved")
+	ErrCodeMistyped       = errors.New("short code check character mismatch")
+)
+
+// Стратегии генерации коротких кодов
+const (
+	// StrategyRandom — случайный код с повтором при коллизии
+	StrategyRandom = "random"
+
+	// StrategySequence — ID из последовательности хранилища через ключевую перестановку
+	StrategySequence = "sequence"
+
+	// StrategyWords — читаемые коды из слов (brave-otter-42) с повтором при коллизии
+	StrategyWords = "words"
+)
+
+const (
+	// defaultGenerateAttempts сколько раз пробуем вставить сгенерированный код
+	defaultGenerateAttempts = 10
+
+	// maxBlockedSequenceIDs сколько подряд ID можно пропустить из-за blocklist
+	maxBlockedSequenceIDs = 100
+
+	// defaultCodeWords количество слов в коде для StrategyWords
+	defaultCodeWords = 2
 )
 
 type URLService struct {
-	storage   storage.Storage
-	generator *generator.Generator
-	validator *validator.URLValidator
-	baseURL   string
+	storage          storage.Storage
+	keyspace         *keyspace
+	validator        *validator.URLValidator
+	baseURL          string
+	reserved         *validator.ReservedWords
+	generateAttempts int
+	logger           *slog.Logger
+
+	// Только для StrategySequence
+	sequencer storage.Sequencer
+	sequence  *generator.SequenceGenerator
+
+	// Пул заранее зарезервированных кодов (nil — выключен)
+	pool *codePool
+
+	// Проверка контрольного символа (nil — коды без контрольного символа)
+	checksum *generator.Generator
+
+	// В базе есть коды вида сгенерированного без контрольного символа:
+	// опечатка определяется только после промаха в хранилище
+	checksumLegacy bool
+
+	// Алфавит кодов: его нормализация применяется к вводу при поиске ссылки
+	alphabet generator.Alphabet
+
+	// Списки вредоносных URL (nil — не проверяются)
+	threats ThreatChecker
+
+	// Фоновая проверка целевых URL (nil — выключена)
+	health *healthChecker
+
+	// Ограничение перебора паролей защищенных ссылок
+	passwords *passwordThrottle
+
+	// Слоты проверки паролей: PBKDF2 не занимает больше GOMAXPROCS ядер
+	passwordSlots chan struct{}
+
+	// Хосты сервиса и других сокращателей; chainClient раскрывает их ссылки (nil — отказ)
+	ownHosts    *validator.DomainSet
+	shorteners  *validator.DomainSet
+	chainClient *http.Client
+
+	// Счетчики генерации кодов для мониторинга заполнения пространства ключей
+	codesGenerated atomic.Int64
+	codeCollisions atomic.Int64
+	codesExhausted atomic.Int64
 }
 
 type Config struct {
 	Storage    storage.Storage
 	BaseURL    string
 	CodeLength int
+
+	// CodeAlphabet алфавит кодов: base62 (по умолчанию), lowercase, crockford,
+	// unambiguous или custom:<символы>
+	CodeAlphabet string
+
+	// CodeStrategy способ генерации кодов: random (по умолчанию) или sequence
+	CodeStrategy string
+
+	// CodeSecret ключ перестановки для StrategySequence
+	CodeSecret []byte
+
+	// CodeChecksum добавляет к сгенерированным кодам контрольный символ,
+	// чтобы отсекать опечатки до обращения к хранилищу
+	CodeChecksum bool
+
+	// CodeChecksumLegacy для баз, где CodeChecksum включен поверх старых кодов:
+	// коды вида сгенерированного ищутся в хранилище, даже если контрольный
+	// символ не сходится, и кастомные коды такого вида не обязаны его иметь
+	CodeChecksumLegacy bool
+
+	// AllowedSchemes разрешенные схемы целевых URL (nil = http, https)
+	AllowedSchemes []string
+
+	// TrackingParams параметры query, удаляемые при нормализации целевых URL
+	// (nil = validator.DefaultTrackingParams, пустой список = ничего не удалять)
+	TrackingParams []string
+
+	// SortQueryParams сортирует параметры query целевых URL по имени
+	SortQueryParams bool
+
+	// DomainPolicy разрешенные и запрещенные домены целевых URL (nil = любые)
+	DomainPolicy validator.DomainChecker
+
+	// ThreatList списки вредоносных URL: проверяются при создании ссылки и при переходе
+	ThreatList ThreatChecker
+
+	// ServiceDomains домены, на которых сервис отвечает помимо хоста BaseURL
+	// Ссылки на них отклоняются, чтобы не создавать циклы редиректов
+	ServiceDomains []string
+
+	// ShortenerHosts хосты других сокращателей (nil = DefaultShortenerHosts)
+	ShortenerHosts []string
+
+	// ShortenerClient раскрывает ссылки сокращателей: сохраняется конечный адрес
+	// nil — такие ссылки отклоняются. Редиректы клиент не выполняет сам,
+	// внутренние адреса должен отклонять транспорт (validator.NewSafeTransport)
+	ShortenerClient *http.Client
+
+	// HealthCheckInterval как часто перепроверяется целевой URL каждой ссылки (0 = не проверяется)
+	HealthCheckInterval time.Duration
+
+	// HealthCheckConcurrency одновременных проверок (0 = 4)
+	HealthCheckConcurrency int
+
+	// HealthCheckHostDelay минимальный промежуток между запросами к одному хосту
+	HealthCheckHostDelay time.Duration
+
+	// HealthCheckClient клиент проверок (nil = таймаут 10 секунд и validator.NewSafeTransport);
+	// редиректы проверяются сервисом, внутренние адреса должен отклонять транспорт клиента
+	HealthCheckClient *http.Client
+
+	// Resolver проверяет, куда разрешается хост целевого URL: внутренние адреса
+	// отклоняются. nil — проверяются только IP-литералы
+	Resolver validator.Resolver
+
+	// CodeCaseInsensitive коды без учета регистра: генерируются и сохраняются
+	// в нижнем регистре. Хранилище переключается отдельно (storage.CaseInsensitiveCodes)
+	CodeCaseInsensitive bool
+
+	// CodeWords количество слов в коде для StrategyWords (0 = 2)
+	CodeWords int
+
+	// CodeWordSeparator разделитель слов для StrategyWords: "-" (по умолчанию) или "_"
+	CodeWordSeparator string
+
+	// CodePoolSize размер пула заранее зарезервированных кодов (0 = без пула)
+	CodePoolSize int
+
+	// CodePoolLowWatermark порог, ниже которого пул пополняется (0 = четверть размера)
+	CodePoolLowWatermark int
+
+	// CodePoolReservationTTL срок резерва кода пула (0 = сутки)
+	// Более старый резерв считается брошенным упавшим экземпляром и снимается
+	CodePoolReservationTTL time.Duration
+
+	// Blocklist запрещенные слова для сгенерированных и кастомных кодов (nil = без фильтра)
+	Blocklist *blocklist.Blocklist
+
+	// CodeGrowthThreshold доля коллизий, при которой длина случайных кодов
+	// увеличивается на единицу (0 = длина фиксирована)
+	CodeGrowthThreshold float64
+
+	// ReservedWords коды, которые нельзя занимать (пути сервиса и пр.); nil = нет
+	ReservedWords *validator.ReservedWords
+
+	// GenerateAttempts бюджет попыток вставки случайного кода (0 = по умолчанию)
+	GenerateAttempts int
+
+	// Logger для предупреждений о коллизиях (nil = slog.Default)
+	Logger *slog.Logger
 }
 
-func NewURLService(cfg Config) *URLService {
+func NewURLService(cfg Config) (*URLService, error) {
 	codeLength := cfg.CodeLength
 	if codeLength == 0 {
 		codeLength = 6
 	}
 
-	return &URLService{
-		storage:   cfg.Storage,
-		generator: generator.NewGenerator(codeLength),
-		validator: validator.NewURLValidator(),
-		baseURL:   cfg.BaseURL,
+	attempts := cfg.GenerateAttempts
+	if attempts <= 0 {
+		attempts = defaultGenerateAttempts
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	alphabet, err := generator.ParseAlphabet(cfg.CodeAlphabet)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CodeCaseInsensitive {
+		if alphabet, err = alphabet.CaseInsensitive(); err != nil {
+			return nil, err
+		}
+	}
+	genOpts := []generator.Option{generator.WithAlphabet(alphabet)}
+	if cfg.Blocklist != nil {
+		genOpts = append(genOpts, generator.WithFilter(cfg.Blocklist))
+	}
+	if cfg.ReservedWords != nil {
+		genOpts = append(genOpts, generator.WithFilter(cfg.ReservedWords))
+	}
+	if cfg.CodeChecksum {
+		genOpts = append(genOpts, generator.WithChecksum())
+	}
+
+	validatorOpts := []validator.Option{
+		validator.WithBlocklist(cfg.Blocklist),
+		validator.WithReservedWords(cfg.ReservedWords),
+	}
+	if cfg.CodeCaseInsensitive {
+		validatorOpts = append(validatorOpts, validator.WithCaseInsensitiveCodes())
+	}
+	if len(cfg.AllowedSchemes) > 0 {
+		validatorOpts = append(validatorOpts, validator.WithAllowedSchemes(cfg.AllowedSchemes...))
+	}
+	if cfg.Resolver != nil {
+		validatorOpts = append(validatorOpts, validator.WithResolver(cfg.Resolver))
+	}
+	if cfg.DomainPolicy != nil {
+		validatorOpts = append(validatorOpts, validator.WithDomainPolicy(cfg.DomainPolicy))
+	}
+	if cfg.TrackingParams != nil {
+		validatorOpts = append(validatorOpts, validator.WithTrackingParams(cfg.TrackingParams...))
+	}
+	if cfg.SortQueryParams {
+		validatorOpts = append(validatorOpts, validator.WithQuerySorting(true))
+	}
+
+	ownHosts, err := newOwnHosts(cfg.BaseURL, cfg.ServiceDomains)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service domains: %w", err)
+	}
+
+	shortenerHosts := cfg.ShortenerHosts
+	if shortenerHosts == nil {
+		shortenerHosts = DefaultShortenerHosts
+	}
+	shorteners, err := validator.NewDomainSet(shortenerHosts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shortener hosts: %w", err)
+	}
+
+	s := &URLService{
+		storage:          cfg.Storage,
+		keyspace:         newKeyspace(codeLength, cfg.CodeGrowthThreshold, genOpts),
+		validator:        validator.NewURLValidator(validatorOpts...),
+		alphabet:         alphabet,
+		baseURL:          cfg.BaseURL,
+		reserved:         cfg.ReservedWords,
+		threats:          cfg.ThreatList,
+		ownHosts:         ownHosts,
+		shorteners:       shorteners,
+		chainClient:      newChainClient(cfg.ShortenerClient),
+		passwords:        newPasswordThrottle(),
+		passwordSlots:    make(chan struct{}, runtime.GOMAXPROCS(0)),
+		generateAttempts: attempts,
+		logger:           logger,
+	}
+
+	if cfg.CodeChecksum {
+		s.checksum = generator.NewGenerator(codeLength, genOpts...)
+		s.checksumLegacy = cfg.CodeChecksumLegacy
+	}
+
+	switch cfg.CodeStrategy {
+	case "", StrategyRandom:
+	case StrategyWords:
+		if cfg.CodeChecksum {
+			return nil, errors.New("code checksum is not supported with word codes")
+		}
+
+		words := cfg.CodeWords
+		if words == 0 {
+			words = defaultCodeWords
+		}
+		separator := cfg.CodeWordSeparator
+		if separator == "" {
+			separator = "-"
+		}
+
+		wordGen, err := generator.NewWordGenerator(words, separator, genOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create word generator: %w", err)
+		}
+
+		// Длина словесных кодов не растет — пространство задается числом слов
+		s.keyspace = newWordKeyspace(wordGen)
+	case StrategySequence:
+		sequencer, ok := cfg.Storage.(storage.Sequencer)
+		if !ok {
+			return nil, fmt.Errorf("storage %T does not support sequence codes", cfg.Storage)
+		}
+
+		sequence, err := generator.NewSequenceGenerator(codeLength, cfg.CodeSecret, genOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sequence generator: %w", err)
+		}
+
+		s.sequencer = sequencer
+		s.sequence = sequence
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, cfg.CodeStrategy)
+	}
+
+	// Длина случайных кодов выводится из числа сохраненных кодов и переживает перезапуск
+	if counter, ok := cfg.Storage.(storage.CodeCounter); ok && s.sequence == nil && s.keyspace.adaptive() {
+		s.keyspace.startSync(counter, logger)
+	}
+
+	if cfg.CodePoolSize > 0 {
+		// Коды из последовательности уникальны сами по себе — пул им не нужен
+		if s.sequence != nil {
+			return nil, errors.New("code pool is only supported with random code strategy")
+		}
+
+		reserver, ok := cfg.Storage.(storage.CodeReserver)
+		if !ok {
+			return nil, fmt.Errorf("storage %T does not support code pool", cfg.Storage)
+		}
+
+		s.pool = newCodePool(reserver, s.keyspace.generate, cfg.CodePoolSize, cfg.CodePoolLowWatermark, cfg.CodePoolReservationTTL, logger)
+		s.pool.start()
+	}
+
+	if cfg.HealthCheckInterval > 0 {
+		tracker, ok := cfg.Storage.(storage.HealthTracker)
+		if !ok {
+			return nil, fmt.Errorf("storage %T does not support link health checks", cfg.Storage)
+		}
+
+		s.health = newHealthChecker(tracker, s.validator, cfg.HealthCheckClient, cfg.HealthCheckInterval,
+			cfg.HealthCheckConcurrency, cfg.HealthCheckHostDelay, logger)
+		s.health.start()
 	}
+
+	return s, nil
+}
+
+// Close останавливает фоновые задачи сервиса и возвращает невыданные коды пула
+func (s *URLService) Close(ctx context.Context) error {
+	if s.health != nil {
+		s.health.close()
+	}
+	s.keyspace.close()
+
+	if s.pool == nil {
+		return nil
+	}
+	return s.pool.close(ctx)
 }
 
 func (s *URLService) ShortenURL(ctx context.Context, req *model.CreateURLRequest) (*model.CreateURLResponse, error) {
 	normalizedURL := s.validator.NormalizeURL(req.URL)
 
-	if err := s.validator.ValidateURL(req.URL); err != nil {
+	// Проверяем уже нормализованный URL — именно он станет целью редиректа
+	if err := s.validator.ValidateURL(ctx, normalizedURL); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
 	}
 
-	var (
-		shortCode string
-		err       error
-	)
+	// Ссылки других сокращателей раскрываются — дальше проверяется конечный адрес
+	normalizedURL, err := s.resolveDestination(ctx, normalizedURL)
+	if err != nil {
+		return nil, err
+	}
 
-	if req.CustomCode != "" {
-		if err := s.validator.ValidateCustomCode(req.CustomCode); err != nil {
-			return nil, fmt.Errorf("invalid custom code: %w", err)
-		}
-		shortCode = req.CustomCode
-	} else {
-		shortCode, err = s.generateUniqueCode(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate code: %w", err)
+	if err := s.validator.ValidateDomain(normalizedURL); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDomainForbidden, err)
+	}
+
+	if match, flagged := s.CheckThreat(normalizedURL); flagged {
+		return nil, fmt.Errorf("%w: %s", ErrURLMalicious, match.ThreatType)
+	}
+
+	redirectType := req.RedirectType
+	if redirectType == 0 {
+		redirectType = model.DefaultRedirectType
+	}
+	if !isRedirectType(redirectType) {
+		return nil, ErrInvalidRedirect
+	}
+
+	if req.MaxClicks < 0 {
+		return nil, ErrInvalidMaxClicks
+	}
+	if _, ok := s.storage.(storage.ClickLimiter); req.MaxClicks > 0 && !ok {
+		return nil, ErrClickLimitDisabled
+	}
+
+	if utf8.RuneCountInString(req.Password) > maxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+	var passwordHash string
+	if req.Password != "" {
+		if passwordHash, err = s.hashPassword(ctx, req.Password); err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
 		}
 	}
+
 	// Вычисляем время истечения
 	var expiresAt *time.Time
 	if req.ExpiresIn > 0 {
@@ -77,43 +460,79 @@ func (s *URLService) ShortenURL(ctx context.Context, req *model.CreateURLRequest
 	}
 
 	url := &model.URL{
-		OriginalURL: normalizedURL,
-		ShortCode:   shortCode,
-		CreatedAt:   time.Now(),
-		ExpiresAt:   expiresAt,
-		ClickCount:  0,
+		OriginalURL:  normalizedURL,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    expiresAt,
+		ClickCount:   0,
+		RedirectType: redirectType,
+		Interstitial: req.Interstitial,
+		PasswordHash: passwordHash,
+		MaxClicks:    req.MaxClicks,
 	}
 
-	// save on storage
-	err = s.storage.Save(ctx, url)
-	if err != nil {
-		if errors.Is(err, storage.ErrDuplicateCode) {
-			return nil, ErrCodeAlreadyUsed
+	if req.CustomCode != "" {
+		if err := s.validateCustomCode(req.CustomCode); err != nil {
+			if errors.Is(err, validator.ErrBlockedCode) {
+				return nil, ErrCodeBlocked
+			}
+			if errors.Is(err, validator.ErrReservedCode) {
+				return nil, ErrCodeReserved
+			}
+			if errors.Is(err, ErrCodeMistyped) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCode, err)
+		}
+
+		url.ShortCode = s.validator.NormalizeCode(req.CustomCode)
+		if err := s.storage.Save(ctx, url); err != nil {
+			if errors.Is(err, storage.ErrDuplicateCode) {
+				return nil, ErrCodeAlreadyUsed
+			}
+			return nil, fmt.Errorf("failed to save url: %w", err)
+		}
+	} else {
+		if err := s.saveWithGeneratedCode(ctx, url); err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to save url: %w", err)
 	}
 
 	return &model.CreateURLResponse{
-		ShortURL:    s.buildShortURL(shortCode),
-		ShortCode:   shortCode,
-		OriginalURL: normalizedURL,
-		ExpiresAt:   expiresAt,
+		ShortURL:          s.buildShortURL(url.ShortCode),
+		ShortCode:         url.ShortCode,
+		OriginalURL:       normalizedURL,
+		ExpiresAt:         expiresAt,
+		RedirectType:      redirectType,
+		Interstitial:      url.Interstitial,
+		PasswordProtected: passwordHash != "",
+		MaxClicks:         url.MaxClicks,
 	}, nil
 }
 
 func (s *URLService) GetOriginalURL(ctx context.Context, shortCode string) (string, error) {
-	url, err := s.storage.GetByShortCode(ctx, shortCode)
+	url, err := s.GetURL(ctx, shortCode)
+	if err != nil {
+		return "", err
+	}
+
+	return url.OriginalURL, nil
+}
+
+// GetURL возвращает действующую ссылку: цель и параметры редиректа
+// url.ShortCode — код в том виде, в каком он сохранен
+func (s *URLService) GetURL(ctx context.Context, shortCode string) (*model.URL, error) {
+	url, err := lookupCode(ctx, s, shortCode, s.storage.GetByShortCode)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
-			return "", ErrURLNotFound
+			return nil, ErrURLNotFound
 		}
 		if errors.Is(err, storage.ErrExpired) {
-			return "", ErrURLExpired
+			return nil, ErrURLExpired
 		}
-		return "", fmt.Errorf("failed to get url: %w", err)
+		return nil, fmt.Errorf("failed to get url: %w", err)
 	}
 
-	return url.OriginalURL, nil
+	return url, nil
 }
 
 func (s *URLService) RegisterClick(ctx context.Context, shortCode string) error {
@@ -136,14 +555,41 @@ func (s *URLService) RegisterClick(ctx context.Context, shortCode string) error
 	return nil
 }
 
+// ConsumeClick синхронно учитывает переход по ссылке с лимитом переходов:
+// учет и проверка лимита — одна операция хранилища. Ссылки без лимита
+// учитываются асинхронно через RegisterClick
+func (s *URLService) ConsumeClick(ctx context.Context, shortCode string) error {
+	limiter, ok := s.storage.(storage.ClickLimiter)
+	if !ok {
+		return ErrClickLimitDisabled
+	}
+
+	if err := limiter.ConsumeClick(ctx, shortCode); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return ErrURLNotFound
+		case errors.Is(err, storage.ErrExpired):
+			return ErrURLExpired
+		case errors.Is(err, storage.ErrClicksExhausted):
+			return ErrClicksExhausted
+		}
+		return fmt.Errorf("failed to consume click: %w", err)
+	}
+
+	return nil
+}
+
 func (s *URLService) GetStats(ctx context.Context, shortCode string) (*model.Stats, error) {
-	stats, err := s.storage.GetStats(ctx, shortCode)
+	stats, err := lookupCode(ctx, s, shortCode, s.storage.GetStats)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return nil, ErrURLNotFound
 		}
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
+
+	stats.Health = model.HealthOf(stats.LastStatus, stats.LastCheckedAt)
+	redactProtected(stats)
 	return stats, nil
 }
 
@@ -157,29 +603,182 @@ func (s *URLService) DeleteURL(ctx context.Context, shortCode string) error {
 	return nil
 }
 
-// generateUniqueCode генерирует уникальный короткий код
-func (s *URLService) generateUniqueCode(ctx context.Context) (string, error) {
-	const maxAttempts = 5
+// lookupCode ищет ссылку по коду, а если не нашел — по коду, нормализованному
+// алфавитом (crockford: регистр, O как 0, I и L как 1)
+// Кастомные коды сохраняются как введены, поэтому сначала ищем код без изменений
+func lookupCode[T any](ctx context.Context, s *URLService, code string, get func(context.Context, string) (T, error)) (T, error) {
+	found, err := get(ctx, code)
+	if !errors.Is(err, storage.ErrNotFound) {
+		return found, err
+	}
 
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		code, err := s.generator.Generate()
+	if normalized := s.alphabet.Normalize(code); normalized != code {
+		return get(ctx, normalized)
+	}
+	return found, err
+}
+
+// saveWithGeneratedCode сохраняет URL со сгенерированным кодом
+// Если бюджет попыток исчерпан, а длина кодов может расти, коды удлиняются
+// и попытки повторяются — пространство ключей не должно приводить к ошибке
+func (s *URLService) saveWithGeneratedCode(ctx context.Context, url *model.URL) error {
+	err := s.insertGeneratedCode(ctx, url)
+
+	if errors.Is(err, ErrCodeExhausted) && s.sequence == nil && s.keyspace.grow() {
+		s.logCodeLengthGrowth("attempts exhausted")
+		err = s.insertGeneratedCode(ctx, url)
+	}
+
+	if errors.Is(err, ErrCodeExhausted) {
+		s.codesExhausted.Add(1)
+	}
+
+	return err
+}
+
+// insertGeneratedCode вставляет URL с новым кодом
+// Уникальность обеспечивает сама вставка: при ErrDuplicateCode берем новый код,
+// пока не исчерпан бюджет попыток — так нет гонки между проверкой и записью
+func (s *URLService) insertGeneratedCode(ctx context.Context, url *model.URL) error {
+	for attempt := 1; attempt <= s.generateAttempts; attempt++ {
+		code, pooled, err := s.nextCode(ctx)
 		if err != nil {
-			return "", err
+			return fmt.Errorf("failed to generate code: %w", err)
 		}
 
-		// Проверяем уникальность
-		_, err = s.storage.GetByShortCode(ctx, code)
-		if errors.Is(err, storage.ErrNotFound) {
-			// Код свободен!
-			return code, nil
+		url.ShortCode = code
+		err = s.storage.Save(ctx, url)
+
+		// Резерв кода из пула больше не нужен: код либо выдан, либо уже занят
+		if pooled {
+			s.pool.done(code)
+		}
+
+		collision := errors.Is(err, storage.ErrDuplicateCode)
+		if err != nil && !collision {
+			return fmt.Errorf("failed to save url: %w", err)
+		}
+
+		// Доля коллизий случайных кодов — оценка заполнения пространства ключей
+		if s.sequence == nil && s.keyspace.observe(len(code), collision) {
+			s.logCodeLengthGrowth("collision rate above threshold")
+		}
+
+		if err == nil {
+			s.codesGenerated.Add(1)
+			return nil
 		}
 
-		// Код занят, пробуем еще раз
+		// Код занят — фиксируем коллизию и пробуем еще раз
+		s.codeCollisions.Add(1)
+		s.logger.Warn("short code collision",
+			"code", code,
+			"attempt", attempt,
+		)
 	}
 
-	return "", errors.New("failed to generate unique code after multiple attempts")
+	return fmt.Errorf("%w after %d attempts", ErrCodeExhausted, s.generateAttempts)
+}
+
+func (s *URLService) logCodeLengthGrowth(reason string) {
+	length, _, size := s.keyspace.state()
+	s.logger.Warn("short code length increased",
+		"length", length,
+		"keyspace", size,
+		"reason", reason,
+	)
+}
+
+// nextCode возвращает очередного кандидата в короткие коды
+// Коды из последовательности не пересекаются между собой, но могут
+// совпасть с кастомным кодом — тогда вставка повторится со следующим ID
+// pooled сообщает, что код взят из пула и с него нужно снять резерв
+func (s *URLService) nextCode(ctx context.Context) (code string, pooled bool, err error) {
+	if s.sequence != nil {
+		// Коды, отвергнутые blocklist, пропускаем вместе с их ID
+		for attempt := 0; attempt < maxBlockedSequenceIDs; attempt++ {
+			id, err := s.sequencer.NextID(ctx)
+			if err != nil {
+				return "", false, err
+			}
+
+			code, err := s.sequence.Code(id)
+			if errors.Is(err, generator.ErrBlockedCode) {
+				continue
+			}
+			return code, false, err
+		}
+		return "", false, generator.ErrBlockedCode
+	}
+
+	// Пул пуст — не ждем пополнения, генерируем на месте
+	if s.pool != nil {
+		if code, ok := s.pool.take(); ok {
+			return code, true, nil
+		}
+	}
+
+	code, err = s.keyspace.generate()
+	return code, false, err
+}
+
+// ReservedConflicts возвращает существующие ссылки, коды которых совпадают
+// с зарезервированными словами — такие ссылки перекрыты маршрутами сервиса
+func (s *URLService) ReservedConflicts(ctx context.Context) ([]string, error) {
+	if s.reserved == nil {
+		return nil, nil
+	}
+
+	var conflicts []string
+	for _, word := range s.reserved.List() {
+		_, err := s.storage.GetByShortCode(ctx, word)
+		switch {
+		case err == nil, errors.Is(err, storage.ErrExpired):
+			conflicts = append(conflicts, word)
+		case errors.Is(err, storage.ErrNotFound):
+		default:
+			return conflicts, fmt.Errorf("failed to check reserved code %q: %w", word, err)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// GenerationStats возвращает счетчики генерации кодов с момента запуска
+func (s *URLService) GenerationStats() model.GenerationStats {
+	length, rate, size := s.keyspace.state()
+
+	stats := model.GenerationStats{
+		Generated:     s.codesGenerated.Load(),
+		Collisions:    s.codeCollisions.Load(),
+		Exhausted:     s.codesExhausted.Load(),
+		CodeLength:    length,
+		CollisionRate: rate,
+		KeyspaceSize:  size,
+	}
+
+	if utilization := s.keyspace.utilization(); utilization >= 0 {
+		stats.Utilization = &utilization
+	}
+
+	if s.pool != nil {
+		available := s.pool.available()
+		stats.PoolAvailable = &available
+	}
+
+	return stats
 }
 
 func (s *URLService) buildShortURL(code string) string {
 	return s.baseURL + "/" + code
 }
+
+// isRedirectType сообщает, поддерживается ли статус редиректа
+func isRedirectType(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,926 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+	"github.com/dmitrycr/ShortUrl/internal/storage"
+	"github.com/dmitrycr/ShortUrl/internal/validator"
+	"github.com/dmitrycr/ShortUrl/pkg/threatlist"
+)
+
+func TestShortenURL_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewURLService(Config{Storage: storage.NewInMemoryStorage(), BaseURL: "http://localhost"})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com"}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("ShortenURL failed: %v", err)
+	}
+
+	if got := svc.GenerationStats().Generated; got != 100 {
+		t.Errorf("Expected 100 generated codes, got %d", got)
+	}
+}
+
+func TestShortenURL_Exhausted(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+	svc, err := NewURLService(Config{Storage: store, BaseURL: "http://localhost", CodeLength: 1, GenerateAttempts: 3})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	// Занимаем все односимвольные коды
+	for _, c := range "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+		store.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: string(c)})
+	}
+
+	_, err = svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com"})
+	if !errors.Is(err, ErrCodeExhausted) {
+		t.Fatalf("Expected ErrCodeExhausted, got %v", err)
+	}
+
+	stats := svc.GenerationStats()
+	if stats.Collisions != 3 || stats.Exhausted != 1 {
+		t.Errorf("Expected 3 collisions and 1 exhaustion, got %+v", stats)
+	}
+}
+
+func TestShortenURL_CodeGrowth(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+	svc, err := NewURLService(Config{
+		Storage:             store,
+		BaseURL:             "http://localhost",
+		CodeLength:          1,
+		GenerateAttempts:    3,
+		CodeGrowthThreshold: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	for _, c := range "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+		store.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: string(c)})
+	}
+
+	// Пространство односимвольных кодов заполнено — длина должна вырасти
+	resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	if len(resp.ShortCode) != 2 {
+		t.Errorf("Expected 2-char code, got %q", resp.ShortCode)
+	}
+
+	stats := svc.GenerationStats()
+	if stats.CodeLength != 2 || stats.Exhausted != 0 {
+		t.Errorf("Expected code length 2 and no exhaustion, got %+v", stats)
+	}
+}
+
+func TestCodeGrowth_DerivedFromStorage(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+
+	// Половина односимвольных кодов уже занята — например, до перезапуска
+	for _, c := range "0123456789abcdefghijklmnopqrstu" {
+		store.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: string(c)})
+	}
+
+	cfg := Config{
+		Storage:             store,
+		BaseURL:             "http://localhost",
+		CodeLength:          1,
+		CodeGrowthThreshold: 0.4,
+	}
+	svc, err := NewURLService(cfg)
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+	defer svc.Close(ctx)
+
+	// Длина выведена из хранилища сразу, без накопления коллизий
+	stats := svc.GenerationStats()
+	if stats.CodeLength != 2 || stats.Utilization == nil || *stats.Utilization != 0 {
+		t.Fatalf("Expected code length 2 with zero utilization, got %+v", stats)
+	}
+
+	resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	if len(resp.ShortCode) != 2 {
+		t.Errorf("Expected 2-char code, got %q", resp.ShortCode)
+	}
+	if got := svc.GenerationStats().Utilization; got == nil || *got != 1.0/3844 {
+		t.Errorf("Expected utilization of one code, got %v", got)
+	}
+
+	// Другой экземпляр (или перезапуск) приходит к той же длине
+	other, err := NewURLService(cfg)
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+	defer other.Close(ctx)
+
+	if got := other.GenerationStats().CodeLength; got != 2 {
+		t.Errorf("Expected restarted service to keep code length 2, got %d", got)
+	}
+}
+
+func TestShortenURL_Sequence(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewURLService(Config{
+		Storage:      storage.NewInMemoryStorage(),
+		BaseURL:      "http://localhost",
+		CodeStrategy: StrategySequence,
+		CodeSecret:   []byte("secret"),
+	})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com"})
+		if err != nil {
+			t.Fatalf("ShortenURL failed: %v", err)
+		}
+		if len(resp.ShortCode) != 6 || seen[resp.ShortCode] {
+			t.Fatalf("Unexpected code %q", resp.ShortCode)
+		}
+		seen[resp.ShortCode] = true
+	}
+
+	if got := svc.GenerationStats().Collisions; got != 0 {
+		t.Errorf("Expected no collisions, got %d", got)
+	}
+}
+
+// trackingStorage отслеживает коды, находящиеся в резерве
+type trackingStorage struct {
+	*storage.InMemoryStorage
+
+	mu       sync.Mutex
+	reserved map[string]bool
+}
+
+func (s *trackingStorage) ReserveCodes(ctx context.Context, codes []string) ([]string, error) {
+	reserved, err := s.InMemoryStorage.ReserveCodes(ctx, codes)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range reserved {
+		s.reserved[code] = true
+	}
+	return reserved, err
+}
+
+func (s *trackingStorage) ReleaseCodes(ctx context.Context, codes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range codes {
+		delete(s.reserved, code)
+	}
+	return s.InMemoryStorage.ReleaseCodes(ctx, codes)
+}
+
+func TestShortenURL_CodePool(t *testing.T) {
+	ctx := context.Background()
+	store := &trackingStorage{InMemoryStorage: storage.NewInMemoryStorage(), reserved: make(map[string]bool)}
+
+	svc, err := NewURLService(Config{Storage: store, BaseURL: "http://localhost", CodePoolSize: 20})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	// Ждем первичного заполнения пула
+	deadline := time.Now().Add(time.Second)
+	for svc.pool.available() < 20 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 0; i < 10; i++ {
+		resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com"})
+		if err != nil {
+			t.Fatalf("ShortenURL failed: %v", err)
+		}
+
+		store.mu.Lock()
+		pooled := store.reserved[resp.ShortCode]
+		store.mu.Unlock()
+		if !pooled {
+			t.Errorf("Expected code %q to come from the pool", resp.ShortCode)
+		}
+	}
+
+	if err := svc.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// После остановки все резервы сняты
+	if n := len(store.reserved); n != 0 {
+		t.Errorf("Expected no reserved codes after Close, got %d", n)
+	}
+}
+
+func TestShortenURL_ReservedWords(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+	reserved := validator.NewReservedWords("health", "api")
+
+	// Ссылка, созданная до появления маршрута
+	store.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: "api"})
+
+	svc, err := NewURLService(Config{Storage: store, BaseURL: "http://localhost", ReservedWords: reserved})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	_, err = svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com", CustomCode: "Health"})
+	if !errors.Is(err, ErrCodeReserved) {
+		t.Errorf("Expected ErrCodeReserved, got %v", err)
+	}
+
+	conflicts, err := svc.ReservedConflicts(ctx)
+	if err != nil {
+		t.Fatalf("ReservedConflicts failed: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != "api" {
+		t.Errorf("Expected [api], got %v", conflicts)
+	}
+}
+
+func TestShortenURL_DomainPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	policy, err := validator.NewDomainPolicy([]string{".example.com"}, []string{"spam.example.com"})
+	if err != nil {
+		t.Fatalf("NewDomainPolicy failed: %v", err)
+	}
+
+	svc, err := NewURLService(Config{Storage: storage.NewInMemoryStorage(), BaseURL: "http://localhost", DomainPolicy: policy})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	// Проверяется домен после нормализации
+	if _, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://WWW.Example.COM./page"}); err != nil {
+		t.Errorf("Expected allowed domain, got %v", err)
+	}
+
+	for _, rawURL := range []string{"https://spam.example.com", "https://example.org"} {
+		_, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: rawURL})
+		if !errors.Is(err, ErrDomainForbidden) {
+			t.Errorf("ShortenURL(%q): expected ErrDomainForbidden, got %v", rawURL, err)
+		}
+	}
+}
+
+func TestShortenURL_RedirectType(t *testing.T) {
+	ctx := context.Background()
+
+	svc, err := NewURLService(Config{Storage: storage.NewInMemoryStorage(), BaseURL: "http://localhost"})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	// Без явного типа — временный редирект
+	resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com/a"})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	if resp.RedirectType != http.StatusFound {
+		t.Errorf("Expected default redirect type 302, got %d", resp.RedirectType)
+	}
+
+	resp, err = svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com/b", RedirectType: http.StatusPermanentRedirect})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	url, err := svc.GetURL(ctx, resp.ShortCode)
+	if err != nil {
+		t.Fatalf("GetURL failed: %v", err)
+	}
+	if url.RedirectStatus() != http.StatusPermanentRedirect {
+		t.Errorf("Expected stored redirect type 308, got %d", url.RedirectStatus())
+	}
+
+	for _, status := range []int{200, 303, 304, 404} {
+		_, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com/c", RedirectType: status})
+		if !errors.Is(err, ErrInvalidRedirect) {
+			t.Errorf("RedirectType %d: expected ErrInvalidRedirect, got %v", status, err)
+		}
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	ctx := context.Background()
+
+	// Тесту не нужна стойкость хеша — только формат и проверка
+	defer func(iterations int) { passwordIterations = iterations }(passwordIterations)
+	passwordIterations = 1000
+
+	svc, err := NewURLService(Config{Storage: storage.NewInMemoryStorage(), BaseURL: "http://localhost"})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com/doc", Password: "s3cret"})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	if !resp.PasswordProtected {
+		t.Error("Expected password protected link")
+	}
+
+	url, err := svc.GetURL(ctx, resp.ShortCode)
+	if err != nil {
+		t.Fatalf("GetURL failed: %v", err)
+	}
+	if url.PasswordHash == "" || strings.Contains(url.PasswordHash, "s3cret") {
+		t.Fatalf("Expected stored hash, got %q", url.PasswordHash)
+	}
+
+	// Статистика не раскрывает адрес защищенной ссылки
+	stats, err := svc.GetStats(ctx, resp.ShortCode)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.OriginalURL != "" {
+		t.Errorf("Expected redacted destination, got %q", stats.OriginalURL)
+	}
+
+	const attacker, owner = "203.0.113.7", "198.51.100.1"
+
+	if err := svc.CheckPassword(ctx, url, "", attacker); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("Expected ErrPasswordRequired, got %v", err)
+	}
+	if err := svc.CheckPassword(ctx, url, "s3cret", attacker); err != nil {
+		t.Errorf("Expected correct password to pass, got %v", err)
+	}
+
+	// Бесплатные попытки, затем блокировка — даже для верного пароля
+	for i := 0; i < passwordFreeAttempts; i++ {
+		if err := svc.CheckPassword(ctx, url, "wrong", attacker); !errors.Is(err, ErrWrongPassword) {
+			t.Fatalf("Attempt %d: expected ErrWrongPassword, got %v", i+1, err)
+		}
+	}
+	if err := svc.CheckPassword(ctx, url, "wrong", attacker); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("Expected last allowed attempt to fail with ErrWrongPassword, got %v", err)
+	}
+	if err := svc.CheckPassword(ctx, url, "s3cret", attacker); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Expected ErrTooManyAttempts, got %v", err)
+	}
+
+	// Перебор с одного адреса не блокирует ссылку для других клиентов
+	if err := svc.CheckPassword(ctx, url, "s3cret", owner); err != nil {
+		t.Errorf("Expected another client to unlock, got %v", err)
+	}
+
+	// Отмененный запрос не ждет слота проверки хеша — ни при проверке, ни при создании
+	for range cap(svc.passwordSlots) {
+		svc.passwordSlots <- struct{}{}
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := svc.CheckPassword(canceled, url, "s3cret", "192.0.2.1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled while slots are busy, got %v", err)
+	}
+	if _, err := svc.ShortenURL(canceled, &model.CreateURLRequest{URL: "https://example.com/doc", Password: "s3cret"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected ShortenURL to wait for a slot, got %v", err)
+	}
+	for range cap(svc.passwordSlots) {
+		<-svc.passwordSlots
+	}
+
+	// Длина считается в символах, а не в байтах
+	if _, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com/doc", Password: strings.Repeat("я", maxPasswordLength)}); err != nil {
+		t.Errorf("Expected %d-character password to be accepted, got %v", maxPasswordLength, err)
+	}
+
+	// Перебор с многих адресов упирается в лимит ссылки
+	var throttled bool
+	for i := range passwordCodeFreeAttempts + 1 {
+		client := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		if err := svc.CheckPassword(ctx, url, "wrong", client); errors.Is(err, ErrTooManyAttempts) {
+			throttled = true
+			break
+		}
+	}
+	if !throttled {
+		t.Error("Expected distributed attempts to hit the per-link limit")
+	}
+
+	if _, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com/doc", Password: strings.Repeat("x", maxPasswordLength+1)}); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Expected ErrInvalidPassword, got %v", err)
+	}
+}
+
+func TestConsumeClick(t *testing.T) {
+	ctx := context.Background()
+
+	svc, err := NewURLService(Config{Storage: storage.NewInMemoryStorage(), BaseURL: "http://localhost"})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	if _, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com/invite", MaxClicks: -1}); !errors.Is(err, ErrInvalidMaxClicks) {
+		t.Errorf("Expected ErrInvalidMaxClicks, got %v", err)
+	}
+
+	resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com/invite", MaxClicks: 1})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	if resp.MaxClicks != 1 {
+		t.Errorf("Expected max clicks 1, got %d", resp.MaxClicks)
+	}
+
+	// Одноразовая ссылка: первый переход проходит, второй — нет
+	if err := svc.ConsumeClick(ctx, resp.ShortCode); err != nil {
+		t.Fatalf("ConsumeClick failed: %v", err)
+	}
+	if err := svc.ConsumeClick(ctx, resp.ShortCode); !errors.Is(err, ErrClicksExhausted) {
+		t.Errorf("Expected ErrClicksExhausted, got %v", err)
+	}
+	if err := svc.ConsumeClick(ctx, "missing"); !errors.Is(err, ErrURLNotFound) {
+		t.Errorf("Expected ErrURLNotFound, got %v", err)
+	}
+}
+
+func TestShortenURL_ThreatList(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+
+	threats := threatlist.New()
+	if err := threats.Add("phish.example/", "phishing"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	// Ссылка создана до того, как адрес попал в список
+	store.Save(ctx, &model.URL{OriginalURL: "https://login.phish.example/", ShortCode: "old123"})
+
+	svc, err := NewURLService(Config{Storage: store, BaseURL: "http://localhost", ThreatList: threats})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	_, err = svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://PHISH.example/login"})
+	if !errors.Is(err, ErrURLMalicious) {
+		t.Errorf("Expected ErrURLMalicious, got %v", err)
+	}
+
+	originalURL, err := svc.GetOriginalURL(ctx, "old123")
+	if err != nil {
+		t.Fatalf("GetOriginalURL failed: %v", err)
+	}
+	if match, flagged := svc.CheckThreat(originalURL); !flagged || match.ThreatType != "phishing" {
+		t.Errorf("Expected existing link to be flagged, got %+v, %v", match, flagged)
+	}
+}
+
+// redirectTransport отвечает редиректами из карты без сети
+type redirectTransport map[string]string
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusNotFound, Header: make(http.Header), Body: http.NoBody, Request: req}
+	if location, ok := t[req.URL.String()]; ok {
+		resp.StatusCode = http.StatusMovedPermanently
+		resp.Header.Set("Location", location)
+	}
+	return resp, nil
+}
+
+func TestShortenURL_ShortenerChain(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func(client *http.Client) *URLService {
+		svc, err := NewURLService(Config{
+			Storage:         storage.NewInMemoryStorage(),
+			BaseURL:         "https://sho.rt",
+			ServiceDomains:  []string{"go.example.com"},
+			ShortenerClient: client,
+		})
+		if err != nil {
+			t.Fatalf("NewURLService failed: %v", err)
+		}
+		return svc
+	}
+
+	offline := newService(nil)
+	for rawURL, want := range map[string]error{
+		"https://sho.rt/abc123":        ErrSelfReferential,
+		"https://GO.example.com/x":     ErrSelfReferential,
+		"https://bit.ly/abc":           ErrShortenerChain,
+		"https://www.tinyurl.com/abc":  ErrShortenerChain,
+		"https://example.com/bit.ly/x": nil,
+	} {
+		if _, err := offline.ShortenURL(ctx, &model.CreateURLRequest{URL: rawURL}); !errors.Is(err, want) {
+			t.Errorf("ShortenURL(%q): expected %v, got %v", rawURL, want, err)
+		}
+	}
+
+	resolving := newService(&http.Client{Transport: redirectTransport{
+		"https://bit.ly/abc":      "https://tinyurl.com/def",
+		"https://tinyurl.com/def": "https://example.com/final?id=1",
+		"https://bit.ly/loop":     "https://sho.rt/abc123",
+		"https://bit.ly/internal": "http://127.0.0.1/admin",
+	}})
+
+	// Сохраняется конечный адрес цепочки
+	resp, err := resolving.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://bit.ly/abc"})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	if resp.OriginalURL != "https://example.com/final?id=1" {
+		t.Errorf("Expected final target, got %q", resp.OriginalURL)
+	}
+
+	for rawURL, want := range map[string]error{
+		"https://bit.ly/loop":     ErrSelfReferential,
+		"https://bit.ly/internal": ErrInvalidURL,
+		"https://bit.ly/missing":  ErrShortenerChain,
+	} {
+		if _, err := resolving.ShortenURL(ctx, &model.CreateURLRequest{URL: rawURL}); !errors.Is(err, want) {
+			t.Errorf("ShortenURL(%q): expected %v, got %v", rawURL, want, err)
+		}
+	}
+}
+
+// probeTransport отвечает статусами по пути и методу без сети
+type probeTransport struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (t *probeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.calls = append(t.calls, req.Method+" "+req.URL.Path)
+	t.mu.Unlock()
+
+	status := http.StatusOK
+	switch req.URL.Path {
+	case "/no-head":
+		if req.Method == http.MethodHead {
+			status = http.StatusMethodNotAllowed
+		}
+	case "/gone":
+		status = http.StatusNotFound
+	case "/down":
+		return nil, errors.New("connection refused")
+	}
+
+	return &http.Response{StatusCode: status, Header: make(http.Header), Body: http.NoBody, Request: req}, nil
+}
+
+func TestHealthChecker_Sweep(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+
+	for code, path := range map[string]string{"ok1234": "/", "nohead": "/no-head", "gone12": "/gone", "down12": "/down"} {
+		store.Save(ctx, &model.URL{OriginalURL: "https://example.com" + path, ShortCode: code, CreatedAt: time.Now()})
+	}
+
+	svc, err := NewURLService(Config{Storage: store, BaseURL: "http://localhost"})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	transport := &probeTransport{}
+	checker := newHealthChecker(store, svc.validator, &http.Client{Transport: transport}, time.Hour, 2, 0, svc.logger)
+
+	checked, broken, err := checker.sweep(ctx)
+	if err != nil || checked != 4 || broken != 2 {
+		t.Fatalf("Expected 4 checked and 2 broken, got %d, %d, %v", checked, broken, err)
+	}
+
+	// Без поддержки HEAD проверка повторяется через GET
+	stats, err := svc.GetStats(ctx, "nohead")
+	if err != nil || stats.Health != model.HealthOK || stats.LastStatus != http.StatusOK {
+		t.Errorf("Expected nohead to be ok after GET fallback, got %+v, %v", stats, err)
+	}
+
+	list, err := svc.ListURLsByHealth(ctx, model.HealthBroken, 0)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Expected 2 broken links, got %+v, %v", list, err)
+	}
+	for _, link := range list {
+		if link.Health != model.HealthBroken {
+			t.Errorf("Expected broken health for %s, got %q", link.ShortCode, link.Health)
+		}
+		if link.OriginalURL != "" {
+			t.Errorf("Expected destination of %s to be hidden, got %q", link.ShortCode, link.OriginalURL)
+		}
+	}
+
+	// Свежие результаты не перепроверяются
+	transport.calls = nil
+	if checked, _, _ := checker.sweep(ctx); checked != 0 || len(transport.calls) != 0 {
+		t.Errorf("Expected no checks within interval, got %d (%v)", checked, transport.calls)
+	}
+
+	// Публичный отчет отдает только битые ссылки
+	for _, health := range []string{"dead", model.HealthOK, model.HealthUnchecked} {
+		if _, err := svc.ListURLsByHealth(ctx, health, 0); !errors.Is(err, ErrInvalidHealth) {
+			t.Errorf("Expected ErrInvalidHealth for %q, got %v", health, err)
+		}
+	}
+}
+
+func TestHealthChecker_BlocksPrivateAddressesAtDial(t *testing.T) {
+	ctx := context.Background()
+
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	// URL перед запросом не проверяется заново — loopback отсекает транспорт,
+	// как и имя, которое после создания ссылки стало разрешаться во внутреннюю сеть
+	checker := newHealthChecker(storage.NewInMemoryStorage(), validator.NewURLValidator(), nil, time.Hour, 1, 0, slog.New(slog.DiscardHandler))
+
+	if _, err := checker.request(ctx, http.MethodGet, server.URL); !errors.Is(err, validator.ErrPrivateAddress) {
+		t.Errorf("Expected ErrPrivateAddress, got %v", err)
+	}
+	if hits != 0 {
+		t.Errorf("Expected no requests to reach the server, got %d", hits)
+	}
+}
+
+func TestCheckCodeAvailability(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+	svc, err := NewURLService(Config{
+		Storage:       store,
+		BaseURL:       "http://localhost",
+		ReservedWords: validator.NewReservedWords("admin"),
+	})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	for _, code := range []string{"promo", "promo2", "promo-2"} {
+		store.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: code})
+	}
+
+	result, err := svc.CheckCodeAvailability(ctx, "promo")
+	if err != nil {
+		t.Fatalf("CheckCodeAvailability failed: %v", err)
+	}
+	if result.Available || result.Reason != ReasonTaken {
+		t.Errorf("Expected taken, got %+v", result)
+	}
+	if len(result.Suggestions) != maxCodeSuggestions || result.Suggestions[0] != "promo_2" {
+		t.Errorf("Expected suggestions starting with promo_2, got %v", result.Suggestions)
+	}
+	for _, suggestion := range result.Suggestions {
+		if suggestion == "promo2" || suggestion == "promo-2" {
+			t.Errorf("Suggested taken code %q", suggestion)
+		}
+	}
+
+	tests := []struct {
+		code      string
+		available bool
+		reason    string
+	}{
+		{"fresh", true, ""},
+		{"ab", false, ReasonInvalid},
+		{"admin", false, ReasonReserved},
+	}
+	for _, tt := range tests {
+		result, err := svc.CheckCodeAvailability(ctx, tt.code)
+		if err != nil {
+			t.Fatalf("CheckCodeAvailability(%q) failed: %v", tt.code, err)
+		}
+		if result.Available != tt.available || result.Reason != tt.reason {
+			t.Errorf("CheckCodeAvailability(%q) = %+v", tt.code, result)
+		}
+	}
+}
+
+func TestShortenURL_Words(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewURLService(Config{
+		Storage:           storage.NewInMemoryStorage(),
+		BaseURL:           "http://localhost",
+		CodeStrategy:      StrategyWords,
+		CodeWords:         2,
+		CodeWordSeparator: "_",
+	})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+
+	// Словесный код должен проходить те же проверки, что и кастомный
+	if strings.Count(resp.ShortCode, "_") != 2 {
+		t.Errorf("Expected word code like brave_otter_42, got %q", resp.ShortCode)
+	}
+	if err := validator.NewURLValidator().ValidateCustomCode(resp.ShortCode); err != nil {
+		t.Errorf("Word code %q rejected by validator: %v", resp.ShortCode, err)
+	}
+}
+
+func TestVerifyCode_Checksum(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewURLService(Config{
+		Storage:      storage.NewInMemoryStorage(),
+		BaseURL:      "http://localhost",
+		CodeChecksum: true,
+	})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	code := resp.ShortCode
+
+	if err := svc.VerifyCode(code); err != nil {
+		t.Fatalf("VerifyCode(%q) failed: %v", code, err)
+	}
+
+	// Заменяем первый символ — опечатка видна без хранилища
+	typo := "0" + code[1:]
+	if code[0] == '0' {
+		typo = "1" + code[1:]
+	}
+	if err := svc.VerifyCode(typo); !errors.Is(err, ErrCodeMistyped) {
+		t.Fatalf("Expected ErrCodeMistyped for %q, got %v", typo, err)
+	}
+
+	corrections, err := svc.SuggestCorrections(ctx, typo)
+	if err != nil {
+		t.Fatalf("SuggestCorrections failed: %v", err)
+	}
+	if len(corrections) != 1 || corrections[0] != code {
+		t.Errorf("Expected correction %q, got %v", code, corrections)
+	}
+
+	// Кастомные коды с разделителями контрольный символ не проверяет
+	if err := svc.VerifyCode("summer-sale"); err != nil {
+		t.Errorf("VerifyCode(summer-sale) failed: %v", err)
+	}
+}
+
+func TestShortenURL_ChecksumVanityCodes(t *testing.T) {
+	ctx := context.Background()
+
+	strict, err := NewURLService(Config{
+		Storage:      storage.NewInMemoryStorage(),
+		BaseURL:      "http://localhost",
+		CodeChecksum: true,
+	})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	// Код вида сгенерированного открывался бы только с верным контрольным символом
+	if _, err := strict.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com", CustomCode: "mylink"}); !errors.Is(err, ErrCodeMistyped) {
+		t.Errorf("Expected ErrCodeMistyped, got %v", err)
+	}
+	if result, err := strict.CheckCodeAvailability(ctx, "mylink"); err != nil || result.Reason != ReasonInvalid {
+		t.Errorf("Expected mylink to be invalid, got %+v, %v", result, err)
+	}
+	if _, err := strict.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com", CustomCode: "summer-sale"}); err != nil {
+		t.Errorf("ShortenURL(summer-sale) failed: %v", err)
+	}
+
+	svc, err := NewURLService(Config{
+		Storage:            storage.NewInMemoryStorage(),
+		BaseURL:            "http://localhost",
+		CodeChecksum:       true,
+		CodeChecksumLegacy: true,
+	})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	// В режиме совместимости буквенно-цифровые коды не обязаны иметь контрольный символ
+	for _, code := range []string{"summer2024", "blackfriday", "mylink"} {
+		resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com", CustomCode: code})
+		if err != nil {
+			t.Fatalf("ShortenURL(%q) failed: %v", code, err)
+		}
+		if resp.ShortCode != code {
+			t.Errorf("Expected code %q, got %q", code, resp.ShortCode)
+		}
+	}
+
+	suggestions, err := svc.SuggestCodes(ctx, "mylink")
+	if err != nil {
+		t.Fatalf("SuggestCodes failed: %v", err)
+	}
+	if len(suggestions) != maxCodeSuggestions {
+		t.Errorf("Expected %d suggestions, got %v", maxCodeSuggestions, suggestions)
+	}
+}
+
+func TestShortenURL_CaseInsensitive(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+	if err := store.EnableCaseInsensitiveCodes(ctx); err != nil {
+		t.Fatalf("EnableCaseInsensitiveCodes failed: %v", err)
+	}
+
+	svc, err := NewURLService(Config{
+		Storage:             store,
+		BaseURL:             "http://localhost",
+		CodeCaseInsensitive: true,
+	})
+	if err != nil {
+		t.Fatalf("NewURLService failed: %v", err)
+	}
+
+	resp, err := svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	if resp.ShortCode != strings.ToLower(resp.ShortCode) {
+		t.Errorf("Expected lowercase generated code, got %q", resp.ShortCode)
+	}
+
+	resp, err = svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com", CustomCode: "MyPromo"})
+	if err != nil {
+		t.Fatalf("ShortenURL failed: %v", err)
+	}
+	if resp.ShortCode != "mypromo" {
+		t.Errorf("Expected custom code normalized to mypromo, got %q", resp.ShortCode)
+	}
+
+	// Автокапитализация при вводе не мешает переходу
+	if _, err := svc.GetOriginalURL(ctx, "Mypromo"); err != nil {
+		t.Errorf("GetOriginalURL(Mypromo) failed: %v", err)
+	}
+
+	_, err = svc.ShortenURL(ctx, &model.CreateURLRequest{URL: "https://example.com", CustomCode: "MYPROMO"})
+	if !errors.Is(err, ErrCodeAlreadyUsed) {
+		t.Errorf("Expected ErrCodeAlreadyUsed, got %v", err)
+	}
+}
+
+func TestCodePool_ReservationTTL(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewInMemoryStorage()
+
+	// Резерв, оставленный упавшим экземпляром
+	if _, err := store.ReserveCodes(ctx, []string{"orphan"}); err != nil {
+		t.Fatalf("ReserveCodes failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	var n int
+	generate := func() (string, error) {
+		n++
+		return fmt.Sprintf("code%02d", n), nil
+	}
+	pool := newCodePool(store, generate, 4, 1, 10*time.Millisecond, slog.New(slog.DiscardHandler))
+
+	pool.fill(ctx)
+	if got, _ := store.ReserveCodes(ctx, []string{"orphan"}); len(got) != 1 {
+		t.Error("Expected stale reservation to be reclaimed on refill")
+	}
+
+	// Свои коды с истекшим резервом пул не выдает
+	time.Sleep(20 * time.Millisecond)
+	if code, ok := pool.take(); ok {
+		t.Errorf("Expected expired pool codes to be skipped, got %q", code)
+	}
+}
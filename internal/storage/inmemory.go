@@ -2,25 +2,103 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/dmitrycr/ShortUrl/internal/model"
 )
 
+func init() {
+	Register("memory", func(ctx context.Context, dsn string) (Storage, error) {
+		cfg, err := parseMemoryDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		// memory:// без пути — обычное хранилище без персистентности
+		if cfg.Dir == "" {
+			return NewInMemoryStorage(), nil
+		}
+
+		return NewDurableInMemoryStorage(cfg)
+	})
+}
+
 // InMemoryStorage реализует Storage в памяти для тестов
+// С журналом (NewDurableInMemoryStorage) переживает перезапуск
 type InMemoryStorage struct {
-	mu     sync.RWMutex
-	urls   map[string]*model.URL // short_code -> URL
-	nextID int64
+	mu      sync.RWMutex
+	urls    map[string]*model.URL // short_code -> URL
+	nextID  int64
+	codeSeq int64    // счетчик для NextID
+	journal *journal // nil — без персистентности
+
+	// Резерв пула кодов не журналируется: пул живет в том же процессе
+	reserved map[string]time.Time
+
+	// foldCase — коды сравниваются без учета регистра (ключи в нижнем регистре)
+	foldCase bool
 }
 
 // NewInMemoryStorage создает новое in-memory хранилище
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
-		urls:   make(map[string]*model.URL),
-		nextID: 1,
+		urls:     make(map[string]*model.URL),
+		nextID:   1,
+		reserved: make(map[string]time.Time),
+	}
+}
+
+// NewDurableInMemoryStorage создает in-memory хранилище с журналом на диске
+// Состояние восстанавливается из снапшота и лога в cfg.Dir
+func NewDurableInMemoryStorage(cfg JournalConfig) (*InMemoryStorage, error) {
+	s := NewInMemoryStorage()
+
+	j, err := openJournal(cfg, s.apply, s.restore)
+	if err != nil {
+		return nil, err
+	}
+	s.journal = j
+
+	return s, nil
+}
+
+// parseMemoryDSN разбирает memory:///var/lib/shorturl?snapshot_every=1000&fsync=false
+func parseMemoryDSN(dsn string) (JournalConfig, error) {
+	var cfg JournalConfig
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return cfg, fmt.Errorf("unable to parse memory dsn: %w", err)
+	}
+
+	cfg.Dir = u.Opaque
+	if cfg.Dir == "" {
+		cfg.Dir = u.Host + u.Path
+	}
+
+	query := u.Query()
+	if v := query.Get("snapshot_every"); v != "" {
+		cfg.SnapshotEvery, err = strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid snapshot_every: %w", err)
+		}
+	}
+	if v := query.Get("fsync"); v != "" {
+		sync, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid fsync: %w", err)
+		}
+		cfg.NoSync = !sync
 	}
+
+	return cfg, nil
 }
 
 // Save сохраняет URL в памяти
@@ -29,18 +107,19 @@ func (s *InMemoryStorage) Save(ctx context.Context, url *model.URL) error {
 	defer s.mu.Unlock()
 
 	// Проверяем дубликат
-	if _, exists := s.urls[url.ShortCode]; exists {
+	if _, exists := s.urls[s.key(url.ShortCode)]; exists {
 		return ErrDuplicateCode
 	}
 
-	// Присваиваем ID
-	url.ID = s.nextID
-	s.nextID++
-
-	// Копируем для защиты от изменений
+	// Присваиваем ID; копируем для защиты от изменений
 	urlCopy := *url
-	s.urls[url.ShortCode] = &urlCopy
+	urlCopy.ID = s.nextID
+
+	if err := s.commit(journalRecord{Op: opSave, URL: &urlCopy}); err != nil {
+		return err
+	}
 
+	url.ID = urlCopy.ID
 	return nil
 }
 
@@ -49,7 +128,7 @@ func (s *InMemoryStorage) GetByShortCode(ctx context.Context, code string) (*mod
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	url, exists := s.urls[code]
+	url, exists := s.urls[s.key(code)]
 	if !exists {
 		return nil, ErrNotFound
 	}
@@ -69,13 +148,30 @@ func (s *InMemoryStorage) IncrementClicks(ctx context.Context, code string) erro
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	url, exists := s.urls[code]
+	if _, exists := s.urls[s.key(code)]; !exists {
+		return ErrNotFound
+	}
+
+	return s.commit(journalRecord{Op: opIncrement, Code: code})
+}
+
+// ConsumeClick увеличивает счетчик, если лимит переходов не исчерпан
+func (s *InMemoryStorage) ConsumeClick(ctx context.Context, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	url, exists := s.urls[s.key(code)]
 	if !exists {
 		return ErrNotFound
 	}
+	if url.ExpiresAt != nil && url.ExpiresAt.Before(time.Now()) {
+		return ErrExpired
+	}
+	if url.MaxClicks > 0 && url.ClickCount >= url.MaxClicks {
+		return ErrClicksExhausted
+	}
 
-	url.ClickCount++
-	return nil
+	return s.commit(journalRecord{Op: opIncrement, Code: code})
 }
 
 // GetStats возвращает статистику
@@ -83,18 +179,29 @@ func (s *InMemoryStorage) GetStats(ctx context.Context, code string) (*model.Sta
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	url, exists := s.urls[code]
+	url, exists := s.urls[s.key(code)]
 	if !exists {
 		return nil, ErrNotFound
 	}
 
+	return urlStats(url), nil
+}
+
+// urlStats собирает статистику по ссылке
+func urlStats(url *model.URL) *model.Stats {
 	return &model.Stats{
-		ShortCode:   url.ShortCode,
-		OriginalURL: url.OriginalURL,
-		ClickCount:  url.ClickCount,
-		CreatedAt:   url.CreatedAt,
-		ExpiresAt:   url.ExpiresAt,
-	}, nil
+		ShortCode:         url.ShortCode,
+		OriginalURL:       url.OriginalURL,
+		ClickCount:        url.ClickCount,
+		CreatedAt:         url.CreatedAt,
+		ExpiresAt:         url.ExpiresAt,
+		RedirectType:      url.RedirectStatus(),
+		Interstitial:      url.Interstitial,
+		PasswordProtected: url.PasswordHash != "",
+		MaxClicks:         url.MaxClicks,
+		LastStatus:        url.LastStatus,
+		LastCheckedAt:     url.LastCheckedAt,
+	}
 }
 
 // Delete удаляет URL
@@ -102,24 +209,334 @@ func (s *InMemoryStorage) Delete(ctx context.Context, code string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.urls[code]; !exists {
+	if _, exists := s.urls[s.key(code)]; !exists {
 		return ErrNotFound
 	}
 
-	delete(s.urls, code)
+	return s.commit(journalRecord{Op: opDelete, Code: code})
+}
+
+// NextID возвращает следующее значение счетчика для генерации кодов
+func (s *InMemoryStorage) NextID(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.commit(journalRecord{Op: opSequence}); err != nil {
+		return 0, err
+	}
+	return s.codeSeq, nil
+}
+
+// ReserveCodes резервирует свободные коды
+func (s *InMemoryStorage) ReserveCodes(ctx context.Context, codes []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	reserved := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if _, exists := s.urls[s.key(code)]; exists {
+			continue
+		}
+		if _, exists := s.reserved[s.key(code)]; exists {
+			continue
+		}
+		s.reserved[s.key(code)] = now
+		reserved = append(reserved, code)
+	}
+
+	return reserved, nil
+}
+
+// ReleaseCodes снимает резерв с кодов
+func (s *InMemoryStorage) ReleaseCodes(ctx context.Context, codes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range codes {
+		delete(s.reserved, s.key(code))
+	}
+
 	return nil
 }
 
-// Close ничего не делает для in-memory
+// ReclaimCodes снимает резерв, поставленный раньше olderThan
+func (s *InMemoryStorage) ReclaimCodes(ctx context.Context, olderThan time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var reclaimed int64
+	for code, reservedAt := range s.reserved {
+		if reservedAt.Before(olderThan) {
+			delete(s.reserved, code)
+			reclaimed++
+		}
+	}
+
+	return reclaimed, nil
+}
+
+// TakenCodes возвращает коды, занятые ссылками
+func (s *InMemoryStorage) TakenCodes(ctx context.Context, codes []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var taken []string
+	for _, code := range codes {
+		if _, exists := s.urls[s.key(code)]; exists {
+			taken = append(taken, code)
+		}
+	}
+
+	return taken, nil
+}
+
+// CountCodesByLength возвращает число ссылок для каждой длины кода
+func (s *InMemoryStorage) CountCodesByLength(ctx context.Context) (map[int]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[int]int64)
+	for _, url := range s.urls {
+		counts[len(url.ShortCode)]++
+	}
+
+	return counts, nil
+}
+
+// DueHealthChecks возвращает ссылки, которые пора проверить
+func (s *InMemoryStorage) DueHealthChecks(ctx context.Context, checkedBefore time.Time, limit int) ([]model.URL, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var due []model.URL
+	for _, url := range s.urls {
+		if url.ExpiresAt != nil && !url.ExpiresAt.After(now) {
+			continue
+		}
+		if url.LastCheckedAt != nil && !url.LastCheckedAt.Before(checkedBefore) {
+			continue
+		}
+		due = append(due, *url)
+	}
+
+	// Сначала непроверенные, затем самые давние
+	sort.Slice(due, func(i, j int) bool {
+		a, b := due[i].LastCheckedAt, due[j].LastCheckedAt
+		if (a == nil) != (b == nil) {
+			return a == nil
+		}
+		if a != nil && !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		return due[i].ID < due[j].ID
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// RecordHealth сохраняет результат проверки целевого URL
+func (s *InMemoryStorage) RecordHealth(ctx context.Context, code string, status int, checkedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.urls[s.key(code)]; !exists {
+		return ErrNotFound
+	}
+
+	return s.commit(journalRecord{Op: opHealth, Code: code, Status: status, CheckedAt: &checkedAt})
+}
+
+// ListByHealth возвращает ссылки в указанном состоянии в порядке создания,
+// кроме ссылок с паролем и лимитом переходов
+func (s *InMemoryStorage) ListByHealth(ctx context.Context, health string, limit int) ([]model.Stats, error) {
+	if _, err := healthCondition(health); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*model.URL
+	for _, url := range s.urls {
+		if url.PasswordHash != "" || url.MaxClicks > 0 {
+			continue
+		}
+		if model.HealthOf(url.LastStatus, url.LastCheckedAt) == health {
+			matched = append(matched, url)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID < matched[j].ID })
+
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	list := make([]model.Stats, len(matched))
+	for i, url := range matched {
+		list[i] = *urlStats(url)
+	}
+	return list, nil
+}
+
+// Close сохраняет снапшот и закрывает журнал, если он есть
 func (s *InMemoryStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal == nil {
+		return nil
+	}
+
+	if err := s.journal.compact(s.snapshot()); err != nil {
+		s.journal.close()
+		return err
+	}
+
+	return s.journal.close()
+}
+
+// CodeCaseConflicts возвращает группы кодов, совпадающих без учета регистра
+func (s *InMemoryStorage) CodeCaseConflicts(ctx context.Context) ([][]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	codes := make([]string, 0, len(s.urls))
+	for _, url := range s.urls {
+		codes = append(codes, url.ShortCode)
+	}
+
+	return groupCaseConflicts(codes), nil
+}
+
+// EnableCaseInsensitiveCodes переключает хранилище на сравнение кодов без учета регистра
+func (s *InMemoryStorage) EnableCaseInsensitiveCodes(ctx context.Context) error {
+	conflicts, err := s.CodeCaseConflicts(ctx)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return caseConflictError(conflicts)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.foldCase = true
+
+	urls := make(map[string]*model.URL, len(s.urls))
+	for _, url := range s.urls {
+		urls[s.key(url.ShortCode)] = url
+	}
+	s.urls = urls
+
+	reserved := make(map[string]time.Time, len(s.reserved))
+	for code, reservedAt := range s.reserved {
+		reserved[s.key(code)] = reservedAt
+	}
+	s.reserved = reserved
+
 	return nil
 }
 
+// key возвращает ключ карты для кода
+func (s *InMemoryStorage) key(code string) string {
+	if s.foldCase {
+		return strings.ToLower(code)
+	}
+	return code
+}
+
 // Clear очищает все данные (для тестов)
 func (s *InMemoryStorage) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.urls = make(map[string]*model.URL)
-	s.nextID = 1
+	// Ошибку журнала некому вернуть; состояние в памяти очищается в любом случае
+	if err := s.commit(journalRecord{Op: opClear}); err != nil {
+		s.apply(&journalRecord{Op: opClear})
+	}
+}
+
+// commit записывает операцию в журнал (если он есть) и применяет ее
+// Вызывается под s.mu.Lock
+func (s *InMemoryStorage) commit(rec journalRecord) error {
+	if s.journal == nil {
+		s.apply(&rec)
+		return nil
+	}
+
+	logged, err := s.journal.append(rec)
+	if err != nil {
+		return err
+	}
+	s.apply(logged)
+
+	// Запись уже в логе и переживет падение: неудачное сжатие не отменяет ее,
+	// лог просто сожмется при следующей попытке
+	if s.journal.needsCompaction() {
+		if err := s.journal.compact(s.snapshot()); err != nil {
+			slog.Warn("failed to compact journal", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// apply изменяет состояние в памяти; используется и при записи, и при восстановлении
+func (s *InMemoryStorage) apply(rec *journalRecord) {
+	switch rec.Op {
+	case opSave:
+		urlCopy := *rec.URL
+		s.urls[s.key(urlCopy.ShortCode)] = &urlCopy
+		if urlCopy.ID >= s.nextID {
+			s.nextID = urlCopy.ID + 1
+		}
+	case opIncrement:
+		if url, exists := s.urls[s.key(rec.Code)]; exists {
+			url.ClickCount++
+		}
+	case opHealth:
+		if url, exists := s.urls[s.key(rec.Code)]; exists {
+			url.LastStatus = rec.Status
+			url.LastCheckedAt = rec.CheckedAt
+		}
+	case opDelete:
+		delete(s.urls, s.key(rec.Code))
+	case opSequence:
+		s.codeSeq++
+	case opClear:
+		s.urls = make(map[string]*model.URL)
+		s.reserved = make(map[string]time.Time)
+		s.nextID = 1
+		s.codeSeq = 0
+	}
+}
+
+// snapshot копирует текущее состояние для записи на диск
+func (s *InMemoryStorage) snapshot() *snapshot {
+	snap := &snapshot{
+		NextID:  s.nextID,
+		CodeSeq: s.codeSeq,
+		URLs:    make([]model.URL, 0, len(s.urls)),
+	}
+	for _, url := range s.urls {
+		snap.URLs = append(snap.URLs, *url)
+	}
+	return snap
+}
+
+// restore заменяет состояние содержимым снапшота
+func (s *InMemoryStorage) restore(snap *snapshot) {
+	s.urls = make(map[string]*model.URL, len(snap.URLs))
+	for i := range snap.URLs {
+		url := snap.URLs[i]
+		s.urls[s.key(url.ShortCode)] = &url
+	}
+	s.nextID = snap.NextID
+	s.codeSeq = snap.CodeSeq
 }
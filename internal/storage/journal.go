@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+)
+
+const (
+	journalLogFile      = "wal.log"
+	journalSnapshotFile = "snapshot.json"
+
+	// Заголовок записи: длина payload (4 байта) + CRC32 payload (4 байта)
+	journalHeaderSize = 8
+
+	// maxJournalRecordSize предел payload записи. Целевой URL не длиннее 2048 символов
+	// (model.MaxURLLength); даже с экранированием JSON и остальными полями запись
+	// намного меньше. Длина больше предела — испорченный заголовок, а не повод
+	// выделять гигабайты при старте
+	maxJournalRecordSize = 64 << 10
+
+	// defaultSnapshotEvery количество записей в логе, после которого он сжимается в снапшот
+	defaultSnapshotEvery = 10000
+)
+
+// Операции, которые пишутся в журнал
+const (
+	opSave      = "save"
+	opIncrement = "increment"
+	opDelete    = "delete"
+	opSequence  = "sequence"
+	opClear     = "clear"
+	opHealth    = "health"
+)
+
+var errCorruptRecord = errors.New("corrupt journal record")
+
+// errJournalBroken журнал в неизвестном состоянии: неудачную запись не удалось
+// откатить, и новые записи легли бы после мусора
+var errJournalBroken = errors.New("journal is broken")
+
+// journalFile файл лога; в тестах подменяется, чтобы имитировать сбои записи
+type journalFile interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+}
+
+// JournalConfig настраивает журналирование InMemoryStorage
+type JournalConfig struct {
+	// Dir директория для лога и снапшота
+	Dir string
+
+	// SnapshotEvery после стольких записей лог сжимается в снапшот (0 = по умолчанию)
+	SnapshotEvery int
+
+	// NoSync отключает fsync после каждой записи — быстрее, но последние
+	// операции могут потеряться при падении ОС
+	NoSync bool
+}
+
+// journalRecord одна операция в журнале
+type journalRecord struct {
+	Seq  uint64     `json:"seq"`
+	Op   string     `json:"op"`
+	URL  *model.URL `json:"url,omitempty"`
+	Code string     `json:"code,omitempty"`
+
+	// Для opHealth: статус проверки и ее время
+	Status    int        `json:"status,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// snapshot полное состояние хранилища на момент записи Seq
+type snapshot struct {
+	Seq     uint64      `json:"seq"`
+	NextID  int64       `json:"next_id"`
+	CodeSeq int64       `json:"code_seq"`
+	URLs    []model.URL `json:"urls"`
+}
+
+// journal append-only лог операций с периодическими снапшотами
+// Не потокобезопасен — вызывающий код держит блокировку хранилища
+type journal struct {
+	dir           string
+	file          journalFile
+	offset        int64 // конец последней целой записи
+	seq           uint64
+	records       int
+	snapshotEvery int
+	sync          bool
+
+	// broken причина, по которой журнал отказывается принимать записи
+	broken error
+}
+
+// openJournal открывает журнал в директории и восстанавливает состояние:
+// сначала снапшот, затем записи лога новее снапшота
+func openJournal(cfg JournalConfig, apply func(*journalRecord), restore func(*snapshot)) (*journal, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create journal dir: %w", err)
+	}
+
+	j := &journal{
+		dir:           cfg.Dir,
+		snapshotEvery: cfg.SnapshotEvery,
+		sync:          !cfg.NoSync,
+	}
+	if j.snapshotEvery <= 0 {
+		j.snapshotEvery = defaultSnapshotEvery
+	}
+
+	snap, err := readSnapshot(filepath.Join(cfg.Dir, journalSnapshotFile))
+	if err != nil {
+		return nil, err
+	}
+	if snap != nil {
+		restore(snap)
+		j.seq = snap.Seq
+	}
+
+	file, err := os.OpenFile(filepath.Join(cfg.Dir, journalLogFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	j.file = file
+
+	if err := j.replay(apply); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// replay применяет записи лога, пропуская уже вошедшие в снапшот
+// Недописанный хвост (падение во время записи) обрезается
+func (j *journal) replay(apply func(*journalRecord)) error {
+	reader := bufio.NewReader(j.file)
+	var offset int64
+
+	for {
+		rec, size, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, errCorruptRecord) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if err := j.file.Truncate(offset); err != nil {
+				return fmt.Errorf("failed to truncate journal tail: %w", err)
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read journal: %w", err)
+		}
+
+		offset += size
+		j.records++
+
+		if rec.Seq <= j.seq {
+			continue
+		}
+		apply(rec)
+		j.seq = rec.Seq
+	}
+
+	if _, err := j.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek journal: %w", err)
+	}
+	j.offset = offset
+
+	return nil
+}
+
+// append дописывает операцию в лог и возвращает запись с присвоенным номером
+func (j *journal) append(rec journalRecord) (*journalRecord, error) {
+	rec.Seq = j.seq + 1
+
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode journal record: %w", err)
+	}
+	if len(payload) > maxJournalRecordSize {
+		return nil, fmt.Errorf("journal record too large: %d bytes", len(payload))
+	}
+
+	buf := make([]byte, journalHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[journalHeaderSize:], payload)
+
+	if j.broken != nil {
+		return nil, fmt.Errorf("%w: %v", errJournalBroken, j.broken)
+	}
+
+	if _, err := j.file.Write(buf); err != nil {
+		return nil, j.rollback(fmt.Errorf("failed to write journal: %w", err))
+	}
+
+	if j.sync {
+		if err := j.file.Sync(); err != nil {
+			return nil, j.rollback(fmt.Errorf("failed to sync journal: %w", err))
+		}
+	}
+
+	j.offset += int64(len(buf))
+	j.seq = rec.Seq
+	j.records++
+
+	return &rec, nil
+}
+
+// rollback обрезает лог до последней целой записи после неудачной записи:
+// иначе при восстановлении «неудавшаяся» запись применится, а следующая
+// с тем же Seq пропадет. Если обрезать не удалось, журнал помечается сломанным
+func (j *journal) rollback(cause error) error {
+	if err := j.file.Truncate(j.offset); err != nil {
+		j.broken = fmt.Errorf("failed to truncate journal: %w", err)
+		return fmt.Errorf("%w (%w)", cause, j.broken)
+	}
+	if _, err := j.file.Seek(j.offset, io.SeekStart); err != nil {
+		j.broken = fmt.Errorf("failed to seek journal: %w", err)
+		return fmt.Errorf("%w (%w)", cause, j.broken)
+	}
+
+	return cause
+}
+
+// needsCompaction сообщает, что лог пора сжать в снапшот
+func (j *journal) needsCompaction() bool {
+	return j.records >= j.snapshotEvery
+}
+
+// compact атомарно записывает снапшот и очищает лог
+// Если процесс упадет между шагами, при старте записи лога с Seq <= снапшота будут пропущены
+func (j *journal) compact(snap *snapshot) error {
+	snap.Seq = j.seq
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	path := filepath.Join(j.dir, journalSnapshotFile)
+	tmp := path + ".tmp"
+
+	if err := writeFileSync(tmp, data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	if err := syncDir(j.dir); err != nil {
+		return fmt.Errorf("failed to sync journal dir: %w", err)
+	}
+
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate journal: %w", err)
+	}
+	j.offset = 0
+
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		j.broken = fmt.Errorf("failed to seek journal: %w", err)
+		return j.broken
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	j.records = 0
+	return nil
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+// readRecord читает одну запись; size — число прочитанных байт
+func readRecord(r io.Reader) (*journalRecord, int64, error) {
+	var header [journalHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	checksum := binary.BigEndian.Uint32(header[4:8])
+	if length > maxJournalRecordSize {
+		return nil, 0, errCorruptRecord
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, 0, errCorruptRecord
+	}
+
+	var rec journalRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return nil, 0, errCorruptRecord
+	}
+
+	return &rec, int64(journalHeaderSize + len(payload)), nil
+}
+
+// readSnapshot читает снапшот; отсутствие файла — не ошибка
+func readSnapshot(path string) (*snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	return &snap, nil
+}
+
+// writeFileSync записывает файл и дожидается сброса на диск
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// syncDir сбрасывает на диск метаданные директории (нужно после rename)
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+)
+
+func TestDurableInMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	open := func(snapshotEvery int) *InMemoryStorage {
+		t.Helper()
+		s, err := NewDurableInMemoryStorage(JournalConfig{Dir: dir, SnapshotEvery: snapshotEvery})
+		if err != nil {
+			t.Fatalf("Failed to open storage: %v", err)
+		}
+		return s
+	}
+
+	t.Run("Replay after crash", func(t *testing.T) {
+		s := open(100)
+
+		for _, code := range []string{"aaa111", "bbb222", "ccc333"} {
+			url := &model.URL{OriginalURL: "https://example.com/" + code, ShortCode: code, CreatedAt: time.Now()}
+			if err := s.Save(ctx, url); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+		}
+		s.IncrementClicks(ctx, "aaa111")
+		s.IncrementClicks(ctx, "aaa111")
+		s.Delete(ctx, "bbb222")
+
+		// Имитируем падение: закрываем файл без снапшота
+		s.journal.close()
+
+		restored := open(100)
+		defer restored.Close()
+
+		stats, err := restored.GetStats(ctx, "aaa111")
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+		if stats.ClickCount != 2 {
+			t.Errorf("Expected 2 clicks, got %d", stats.ClickCount)
+		}
+
+		if _, err := restored.GetByShortCode(ctx, "bbb222"); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		url := &model.URL{OriginalURL: "https://example.com/new", ShortCode: "ddd444", CreatedAt: time.Now()}
+		if err := restored.Save(ctx, url); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if url.ID != 4 {
+			t.Errorf("Expected ID 4 after replay, got %d", url.ID)
+		}
+	})
+
+	t.Run("Compaction", func(t *testing.T) {
+		s := open(2)
+
+		for i := 0; i < 5; i++ {
+			if err := s.IncrementClicks(ctx, "aaa111"); err != nil {
+				t.Fatalf("IncrementClicks failed: %v", err)
+			}
+		}
+		s.journal.close()
+
+		restored := open(2)
+		defer restored.Close()
+
+		stats, err := restored.GetStats(ctx, "aaa111")
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+		if stats.ClickCount != 7 {
+			t.Errorf("Expected 7 clicks, got %d", stats.ClickCount)
+		}
+	})
+
+	t.Run("Torn tail", func(t *testing.T) {
+		s := open(100)
+		s.IncrementClicks(ctx, "ccc333")
+		s.journal.close()
+
+		// Дописываем обрывок записи, как при падении во время write
+		f, err := os.OpenFile(filepath.Join(dir, journalLogFile), os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			t.Fatalf("Failed to open log: %v", err)
+		}
+		f.Write([]byte{0, 0, 0, 50, 1, 2})
+		f.Close()
+
+		restored := open(100)
+		defer restored.Close()
+
+		stats, err := restored.GetStats(ctx, "ccc333")
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+		if stats.ClickCount != 1 {
+			t.Errorf("Expected 1 click, got %d", stats.ClickCount)
+		}
+	})
+
+	t.Run("Oversized header", func(t *testing.T) {
+		s := open(100)
+		s.IncrementClicks(ctx, "ccc333")
+		s.journal.close()
+
+		// Испорченная длина в заголовке не должна приводить к выделению гигабайтов
+		f, err := os.OpenFile(filepath.Join(dir, journalLogFile), os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			t.Fatalf("Failed to open log: %v", err)
+		}
+		f.Write([]byte{0xff, 0xff, 0xff, 0xf0, 1, 2, 3, 4, 5, 6})
+		f.Close()
+
+		restored := open(100)
+		defer restored.Close()
+
+		stats, err := restored.GetStats(ctx, "ccc333")
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+		if stats.ClickCount != 2 {
+			t.Errorf("Expected 2 clicks, got %d", stats.ClickCount)
+		}
+	})
+}
+
+func TestDurableInMemoryStorage_CompactionFailure(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s, err := NewDurableInMemoryStorage(JournalConfig{Dir: dir, SnapshotEvery: 1})
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+
+	// Временный файл снапшота занят директорией — сжатие не удастся
+	tmp := filepath.Join(dir, journalSnapshotFile+".tmp")
+	if err := os.Mkdir(tmp, 0o755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+
+	url := &model.URL{OriginalURL: "https://example.com/a", ShortCode: "aaa111", CreatedAt: time.Now()}
+	if err := s.Save(ctx, url); err != nil {
+		t.Fatalf("Save must succeed when only compaction fails, got %v", err)
+	}
+	s.journal.close()
+
+	if err := os.Remove(tmp); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	restored, err := NewDurableInMemoryStorage(JournalConfig{Dir: dir, SnapshotEvery: 1})
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer restored.Close()
+
+	if _, err := restored.GetByShortCode(ctx, "aaa111"); err != nil {
+		t.Errorf("Expected saved url after restart, got %v", err)
+	}
+}
+
+// faultyFile пишет половину буфера и возвращает ошибку, пока включен failWrite
+type faultyFile struct {
+	*os.File
+	failWrite    bool
+	failTruncate bool
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.failWrite {
+		n, _ := f.File.Write(p[:len(p)/2])
+		return n, errors.New("disk full")
+	}
+	return f.File.Write(p)
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return errors.New("read-only filesystem")
+	}
+	return f.File.Truncate(size)
+}
+
+func TestDurableInMemoryStorage_WriteFailure(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s, err := NewDurableInMemoryStorage(JournalConfig{Dir: dir, SnapshotEvery: 100})
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	file := &faultyFile{File: s.journal.file.(*os.File)}
+	s.journal.file = file
+
+	save := func(code string) error {
+		return s.Save(ctx, &model.URL{OriginalURL: "https://example.com/" + code, ShortCode: code, CreatedAt: time.Now()})
+	}
+
+	if err := save("aaa111"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	file.failWrite = true
+	if err := save("failed"); err == nil {
+		t.Fatal("Expected Save to fail on write error")
+	}
+	file.failWrite = false
+
+	// Следующая запись получает тот же Seq, но обрывок неудачной уже обрезан
+	if err := save("bbb222"); err != nil {
+		t.Fatalf("Save after failure failed: %v", err)
+	}
+	if err := save("ccc333"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Обрезать не удалось — журнал больше не принимает записи
+	file.failWrite, file.failTruncate = true, true
+	if err := save("ddd444"); err == nil {
+		t.Fatal("Expected Save to fail on write error")
+	}
+	file.failWrite, file.failTruncate = false, false
+	if err := save("eee555"); !errors.Is(err, errJournalBroken) {
+		t.Errorf("Expected errJournalBroken, got %v", err)
+	}
+	s.journal.close()
+
+	restored, err := NewDurableInMemoryStorage(JournalConfig{Dir: dir, SnapshotEvery: 100})
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer restored.Close()
+
+	for _, code := range []string{"aaa111", "bbb222", "ccc333"} {
+		if _, err := restored.GetByShortCode(ctx, code); err != nil {
+			t.Errorf("Expected %q after replay, got %v", code, err)
+		}
+	}
+	for _, code := range []string{"failed", "ddd444", "eee555"} {
+		if _, err := restored.GetByShortCode(ctx, code); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected failed write %q to be lost, got %v", code, err)
+		}
+	}
+}
@@ -6,14 +6,29 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/dmitrycr/ShortUrl/internal/migrate"
 	"github.com/dmitrycr/ShortUrl/internal/model"
+	"github.com/dmitrycr/ShortUrl/migrations"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
+func init() {
+	open := func(ctx context.Context, dsn string) (Storage, error) {
+		return NewPostgresStorage(ctx, dsn)
+	}
+
+	Register("postgres", open)
+	Register("postgresql", open)
+}
+
 type PostgresStorage struct {
 	pool *pgxpool.Pool
+
+	// foldCase — коды сравниваются без учета регистра; включается при старте
+	foldCase bool
 }
 
 func NewPostgresStorage(ctx context.Context, connString string) (*PostgresStorage, error) {
@@ -44,10 +59,19 @@ func NewPostgresStorage(ctx context.Context, connString string) (*PostgresStorag
 	}, nil
 }
 
+// Migrator возвращает мигратор схемы Postgres поверх пула подключений
+func (s *PostgresStorage) Migrator(opts ...migrate.Option) (*migrate.Migrator, error) {
+	list, err := migrate.Load(migrations.Postgres, "postgres")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(stdlib.OpenDBFromPool(s.pool), migrate.Postgres, list, opts...), nil
+}
+
 func (s *PostgresStorage) Save(ctx context.Context, url *model.URL) error {
 	query := `
-    INSERT INTO urls (original_url, short_code, created_at, expires_at, click_count)
-    VALUES ($1, $2, $3, $4, $5)
+    INSERT INTO urls (original_url, short_code, created_at, expires_at, click_count, redirect_type, interstitial, password_hash, max_clicks)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
     RETURNING id
 `
 
@@ -59,6 +83,10 @@ func (s *PostgresStorage) Save(ctx context.Context, url *model.URL) error {
 		url.CreatedAt,
 		url.ExpiresAt,
 		url.ClickCount,
+		url.RedirectStatus(),
+		url.Interstitial,
+		url.PasswordHash,
+		url.MaxClicks,
 	).Scan(&url.ID)
 
 	if err != nil {
@@ -74,10 +102,9 @@ func (s *PostgresStorage) Save(ctx context.Context, url *model.URL) error {
 
 func (s *PostgresStorage) GetByShortCode(ctx context.Context, code string) (*model.URL, error) {
 	query := `
-        SELECT id, original_url, short_code, created_at, expires_at, click_count
+        SELECT id, original_url, short_code, created_at, expires_at, click_count, redirect_type, interstitial, password_hash, max_clicks
         FROM urls
-        WHERE short_code = $1
-    `
+        WHERE ` + s.codeMatch("$1")
 	var url model.URL
 
 	err := s.pool.QueryRow(ctx, query, code).Scan(
@@ -87,6 +114,10 @@ func (s *PostgresStorage) GetByShortCode(ctx context.Context, code string) (*mod
 		&url.CreatedAt,
 		&url.ExpiresAt,
 		&url.ClickCount,
+		&url.RedirectType,
+		&url.Interstitial,
+		&url.PasswordHash,
+		&url.MaxClicks,
 	)
 
 	if err != nil {
@@ -107,8 +138,7 @@ func (s *PostgresStorage) IncrementClicks(ctx context.Context, code string) erro
 	query := `
 	UPDATE urls
 	SET click_count = click_count + 1
-	WHERE short_code = $1
-`
+	WHERE ` + s.codeMatch("$1")
 	result, err := s.pool.Exec(ctx, query, code)
 	if err != nil {
 		return fmt.Errorf("failed to increment clicks: %w", err)
@@ -121,12 +151,26 @@ func (s *PostgresStorage) IncrementClicks(ctx context.Context, code string) erro
 	return nil
 }
 
+func (s *PostgresStorage) ConsumeClick(ctx context.Context, code string) error {
+	query := fmt.Sprintf(consumeClickQuery, s.codeMatch("$1"), "$2")
+
+	result, err := s.pool.Exec(ctx, query, code, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to consume click: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return clickRefusal(ctx, s, code)
+	}
+
+	return nil
+}
+
 func (s *PostgresStorage) GetStats(ctx context.Context, code string) (*model.Stats, error) {
 	query := `
-		SELECT short_code, original_url, click_count, created_at, expires_at
+		SELECT short_code, original_url, click_count, created_at, expires_at, redirect_type, interstitial, password_hash <> '', max_clicks, ` + healthColumns + `
 		FROM urls
-		WHERE short_code = $1
-	`
+		WHERE ` + s.codeMatch("$1")
 	var stats model.Stats
 
 	err := s.pool.QueryRow(ctx, query, code).Scan(
@@ -135,6 +179,12 @@ func (s *PostgresStorage) GetStats(ctx context.Context, code string) (*model.Sta
 		&stats.ClickCount,
 		&stats.CreatedAt,
 		&stats.ExpiresAt,
+		&stats.RedirectType,
+		&stats.Interstitial,
+		&stats.PasswordProtected,
+		&stats.MaxClicks,
+		&stats.LastStatus,
+		&stats.LastCheckedAt,
 	)
 
 	if err != nil {
@@ -151,8 +201,7 @@ func (s *PostgresStorage) GetStats(ctx context.Context, code string) (*model.Sta
 func (s *PostgresStorage) Delete(ctx context.Context, code string) error {
 	query := `
 		DELETE FROM urls 
-		WHERE short_code = $1
-`
+		WHERE ` + s.codeMatch("$1")
 
 	result, err := s.pool.Exec(ctx, query, code)
 	if err != nil {
@@ -166,6 +215,226 @@ func (s *PostgresStorage) Delete(ctx context.Context, code string) error {
 	return nil
 }
 
+// NextID возвращает следующее значение short_code_seq
+func (s *PostgresStorage) NextID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := s.pool.QueryRow(ctx, "SELECT nextval('short_code_seq')").Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get next id: %w", err)
+	}
+	return id, nil
+}
+
+func (s *PostgresStorage) ReserveCodes(ctx context.Context, codes []string) ([]string, error) {
+	query := `
+		INSERT INTO reserved_codes (code, reserved_at)
+		SELECT c, $2::timestamp FROM unnest($1::text[]) AS c
+		WHERE NOT EXISTS (SELECT 1 FROM urls WHERE ` + s.codeMatch("c") + `)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING code
+	`
+
+	rows, err := s.pool.Query(ctx, query, codes, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve codes: %w", err)
+	}
+
+	reserved, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve codes: %w", err)
+	}
+
+	return reserved, nil
+}
+
+func (s *PostgresStorage) ReleaseCodes(ctx context.Context, codes []string) error {
+	query := `
+		DELETE FROM reserved_codes
+		WHERE code = ANY($1)
+	`
+
+	if _, err := s.pool.Exec(ctx, query, codes); err != nil {
+		return fmt.Errorf("failed to release codes: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) ReclaimCodes(ctx context.Context, olderThan time.Time) (int64, error) {
+	query := `
+		DELETE FROM reserved_codes
+		WHERE reserved_at < $1
+	`
+
+	result, err := s.pool.Exec(ctx, query, olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reclaim codes: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (s *PostgresStorage) TakenCodes(ctx context.Context, codes []string) ([]string, error) {
+	query := `
+		SELECT c FROM unnest($1::text[]) AS c
+		WHERE EXISTS (SELECT 1 FROM urls WHERE ` + s.codeMatch("c") + `)
+	`
+
+	rows, err := s.pool.Query(ctx, query, codes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check codes: %w", err)
+	}
+
+	taken, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to check codes: %w", err)
+	}
+
+	return taken, nil
+}
+
+func (s *PostgresStorage) CountCodesByLength(ctx context.Context) (map[int]int64, error) {
+	rows, err := s.pool.Query(ctx, countCodesByLengthQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count codes: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int64)
+	for rows.Next() {
+		var length int
+		var count int64
+		if err := rows.Scan(&length, &count); err != nil {
+			return nil, fmt.Errorf("failed to count codes: %w", err)
+		}
+		counts[length] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count codes: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (s *PostgresStorage) CodeCaseConflicts(ctx context.Context) ([][]string, error) {
+	rows, err := s.pool.Query(ctx, caseConflictsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find case conflicts: %w", err)
+	}
+
+	codes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to find case conflicts: %w", err)
+	}
+
+	return groupCaseConflicts(codes), nil
+}
+
+// EnableCaseInsensitiveCodes требует примененной миграции FeatureCaseInsensitiveCodes:
+// схема Postgres меняется только через server migrate up (или AUTO_MIGRATE)
+func (s *PostgresStorage) EnableCaseInsensitiveCodes(ctx context.Context) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = 'urls' AND indexname = $1)`
+	if err := s.pool.QueryRow(ctx, query, lowerCodeIndex).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check case-insensitive index: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: run `server migrate up` with CODE_CASE_INSENSITIVE=true", ErrCaseIndexMissing)
+	}
+
+	s.foldCase = true
+	return nil
+}
+
+func (s *PostgresStorage) DueHealthChecks(ctx context.Context, checkedBefore time.Time, limit int) ([]model.URL, error) {
+	query := fmt.Sprintf(dueHealthChecksQuery, "$1", "$2", "$3")
+
+	rows, err := s.pool.Query(ctx, query, time.Now(), checkedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links to check: %w", err)
+	}
+
+	urls, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.URL, error) {
+		var url model.URL
+		err := row.Scan(
+			&url.ID,
+			&url.OriginalURL,
+			&url.ShortCode,
+			&url.CreatedAt,
+			&url.ExpiresAt,
+			&url.ClickCount,
+			&url.LastStatus,
+			&url.LastCheckedAt,
+		)
+		return url, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links to check: %w", err)
+	}
+
+	return urls, nil
+}
+
+func (s *PostgresStorage) RecordHealth(ctx context.Context, code string, status int, checkedAt time.Time) error {
+	query := `
+		UPDATE urls
+		SET last_status = $2, last_checked_at = $3
+		WHERE ` + s.codeMatch("$1")
+
+	result, err := s.pool.Exec(ctx, query, code, status, checkedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record link health: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) ListByHealth(ctx context.Context, health string, limit int) ([]model.Stats, error) {
+	condition, err := healthCondition(health)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(listByHealthQuery, condition, "$1"), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	list, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Stats, error) {
+		var stats model.Stats
+		err := row.Scan(
+			&stats.ShortCode,
+			&stats.OriginalURL,
+			&stats.ClickCount,
+			&stats.CreatedAt,
+			&stats.ExpiresAt,
+			&stats.RedirectType,
+			&stats.Interstitial,
+			&stats.PasswordProtected,
+			&stats.MaxClicks,
+			&stats.LastStatus,
+			&stats.LastCheckedAt,
+		)
+		return stats, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	return list, nil
+}
+
+// codeMatch условие сравнения short_code с параметром с учетом режима регистра
+func (s *PostgresStorage) codeMatch(param string) string {
+	if s.foldCase {
+		return "lower(short_code) = lower(" + param + ")"
+	}
+	return "short_code = " + param
+}
+
 func (s *PostgresStorage) Close() error {
 	s.pool.Close()
 	return nil
@@ -0,0 +1,553 @@
+//go:build cgo
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/migrate"
+	"github.com/dmitrycr/ShortUrl/internal/model"
+	"github.com/dmitrycr/ShortUrl/migrations"
+	"github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	open := func(ctx context.Context, dsn string) (Storage, error) {
+		path, err := sqlitePath(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return NewSQLiteStorage(ctx, path)
+	}
+
+	Register("file", open)
+	Register("sqlite", open)
+}
+
+// SQLiteStorage реализует Storage поверх SQLite
+// Подходит для небольших инсталляций и CI без сервера Postgres.
+// Драйверу нужен cgo: при CGO_ENABLED=0 схемы file:// и sqlite:// не регистрируются
+type SQLiteStorage struct {
+	db *sql.DB
+
+	// foldCase — коды сравниваются без учета регистра; включается при старте
+	foldCase bool
+}
+
+// NewSQLiteStorage открывает (или создает) базу по указанному пути
+// и применяет миграции из migrations/sqlite
+func NewSQLiteStorage(ctx context.Context, path string) (*SQLiteStorage, error) {
+	// _txlock=immediate берет блокировку записи в начале транзакции,
+	// это защищает миграции от гонки между процессами
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate", path)
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database: %w", err)
+	}
+
+	// SQLite сериализует запись, одно подключение избавляет от SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to ping database: %w", err)
+	}
+
+	s := &SQLiteStorage{db: db}
+
+	migrator, err := s.Migrator()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// sqlitePath извлекает путь к файлу базы из DSN
+// Поддерживаются file:///abs/urls.db, file:urls.db и sqlite://data/urls.db
+func sqlitePath(dsn string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse sqlite dsn: %w", err)
+	}
+
+	path := u.Opaque
+	if path == "" {
+		path = u.Host + u.Path
+	}
+
+	if path == "" {
+		return "", fmt.Errorf("sqlite dsn %q has no file path", dsn)
+	}
+
+	return path, nil
+}
+
+// Migrator возвращает мигратор схемы SQLite
+func (s *SQLiteStorage) Migrator(opts ...migrate.Option) (*migrate.Migrator, error) {
+	list, err := migrate.Load(migrations.SQLite, "sqlite")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(s.db, migrate.SQLite, list, opts...), nil
+}
+
+func (s *SQLiteStorage) Save(ctx context.Context, url *model.URL) error {
+	query := `
+		INSERT INTO urls (original_url, short_code, created_at, expires_at, click_count, redirect_type, interstitial, password_hash, max_clicks)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := s.db.ExecContext(
+		ctx,
+		query,
+		url.OriginalURL,
+		url.ShortCode,
+		url.CreatedAt,
+		url.ExpiresAt,
+		url.ClickCount,
+		url.RedirectStatus(),
+		url.Interstitial,
+		url.PasswordHash,
+		url.MaxClicks,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return ErrDuplicateCode
+		}
+		return fmt.Errorf("failed to save url: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get inserted id: %w", err)
+	}
+	url.ID = id
+
+	return nil
+}
+
+func (s *SQLiteStorage) GetByShortCode(ctx context.Context, code string) (*model.URL, error) {
+	query := `
+		SELECT id, original_url, short_code, created_at, expires_at, click_count, redirect_type, interstitial, password_hash, max_clicks
+		FROM urls
+		WHERE ` + s.codeMatch("?")
+	var url model.URL
+
+	err := s.db.QueryRowContext(ctx, query, code).Scan(
+		&url.ID,
+		&url.OriginalURL,
+		&url.ShortCode,
+		&url.CreatedAt,
+		&url.ExpiresAt,
+		&url.ClickCount,
+		&url.RedirectType,
+		&url.Interstitial,
+		&url.PasswordHash,
+		&url.MaxClicks,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get url: %w", err)
+	}
+
+	if url.ExpiresAt != nil && url.ExpiresAt.Before(time.Now()) {
+		return nil, ErrExpired
+	}
+
+	return &url, nil
+}
+
+func (s *SQLiteStorage) IncrementClicks(ctx context.Context, code string) error {
+	query := `
+		UPDATE urls
+		SET click_count = click_count + 1
+		WHERE ` + s.codeMatch("?")
+	result, err := s.db.ExecContext(ctx, query, code)
+	if err != nil {
+		return fmt.Errorf("failed to increment clicks: %w", err)
+	}
+
+	return checkRowsAffected(result)
+}
+
+func (s *SQLiteStorage) ConsumeClick(ctx context.Context, code string) error {
+	query := fmt.Sprintf(consumeClickQuery, s.codeMatch("?"), "?")
+
+	result, err := s.db.ExecContext(ctx, query, code, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to consume click: %w", err)
+	}
+
+	err = checkRowsAffected(result)
+	if errors.Is(err, ErrNotFound) {
+		return clickRefusal(ctx, s, code)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) GetStats(ctx context.Context, code string) (*model.Stats, error) {
+	query := `
+		SELECT short_code, original_url, click_count, created_at, expires_at, redirect_type, interstitial, password_hash <> '', max_clicks, ` + healthColumns + `
+		FROM urls
+		WHERE ` + s.codeMatch("?")
+	var stats model.Stats
+
+	err := s.db.QueryRowContext(ctx, query, code).Scan(
+		&stats.ShortCode,
+		&stats.OriginalURL,
+		&stats.ClickCount,
+		&stats.CreatedAt,
+		&stats.ExpiresAt,
+		&stats.RedirectType,
+		&stats.Interstitial,
+		&stats.PasswordProtected,
+		&stats.MaxClicks,
+		&stats.LastStatus,
+		&stats.LastCheckedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+func (s *SQLiteStorage) Delete(ctx context.Context, code string) error {
+	query := `
+		DELETE FROM urls
+		WHERE ` + s.codeMatch("?")
+
+	result, err := s.db.ExecContext(ctx, query, code)
+	if err != nil {
+		return fmt.Errorf("failed delete url: %w", err)
+	}
+
+	return checkRowsAffected(result)
+}
+
+// NextID атомарно увеличивает счетчик short_code в таблице sequences
+func (s *SQLiteStorage) NextID(ctx context.Context) (int64, error) {
+	query := `
+		INSERT INTO sequences (name, value) VALUES ('short_code', 1)
+		ON CONFLICT (name) DO UPDATE SET value = value + 1
+		RETURNING value
+	`
+	var id int64
+	if err := s.db.QueryRowContext(ctx, query).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get next id: %w", err)
+	}
+	return id, nil
+}
+
+func (s *SQLiteStorage) ReserveCodes(ctx context.Context, codes []string) ([]string, error) {
+	query := `
+		INSERT INTO reserved_codes (code, reserved_at)
+		SELECT ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM urls WHERE ` + s.codeMatch("?") + `)
+		ON CONFLICT (code) DO NOTHING
+	`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	reserved := make([]string, 0, len(codes))
+
+	for _, code := range codes {
+		result, err := tx.ExecContext(ctx, query, code, now, code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve codes: %w", err)
+		}
+
+		if rows, _ := result.RowsAffected(); rows > 0 {
+			reserved = append(reserved, code)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to reserve codes: %w", err)
+	}
+
+	return reserved, nil
+}
+
+func (s *SQLiteStorage) ReleaseCodes(ctx context.Context, codes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, code := range codes {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM reserved_codes WHERE code = ?", code); err != nil {
+			return fmt.Errorf("failed to release codes: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to release codes: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStorage) ReclaimCodes(ctx context.Context, olderThan time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM reserved_codes WHERE reserved_at < ?", olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reclaim codes: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+func (s *SQLiteStorage) TakenCodes(ctx context.Context, codes []string) ([]string, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(codes)), ",")
+	query := "SELECT short_code FROM urls WHERE short_code IN (" + placeholders + ")"
+	if s.foldCase {
+		query = "SELECT lower(short_code) FROM urls WHERE lower(short_code) IN (" + placeholders + ")"
+	}
+
+	args := make([]any, len(codes))
+	for i, code := range codes {
+		if s.foldCase {
+			code = strings.ToLower(code)
+		}
+		args[i] = code
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check codes: %w", err)
+	}
+	defer rows.Close()
+
+	var taken []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("failed to check codes: %w", err)
+		}
+		taken = append(taken, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check codes: %w", err)
+	}
+
+	return taken, nil
+}
+
+func (s *SQLiteStorage) CountCodesByLength(ctx context.Context) (map[int]int64, error) {
+	rows, err := s.db.QueryContext(ctx, countCodesByLengthQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count codes: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int64)
+	for rows.Next() {
+		var length int
+		var count int64
+		if err := rows.Scan(&length, &count); err != nil {
+			return nil, fmt.Errorf("failed to count codes: %w", err)
+		}
+		counts[length] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count codes: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (s *SQLiteStorage) CodeCaseConflicts(ctx context.Context) ([][]string, error) {
+	rows, err := s.db.QueryContext(ctx, caseConflictsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find case conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("failed to find case conflicts: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find case conflicts: %w", err)
+	}
+
+	return groupCaseConflicts(codes), nil
+}
+
+// EnableCaseInsensitiveCodes применяет миграцию FeatureCaseInsensitiveCodes:
+// SQLite и так мигрирует схему при открытии
+func (s *SQLiteStorage) EnableCaseInsensitiveCodes(ctx context.Context) error {
+	conflicts, err := s.CodeCaseConflicts(ctx)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return caseConflictError(conflicts)
+	}
+
+	migrator, err := s.Migrator(migrate.WithFeature(FeatureCaseInsensitiveCodes, true))
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("failed to enable case-insensitive codes: %w", err)
+	}
+
+	s.foldCase = true
+	return nil
+}
+
+func (s *SQLiteStorage) DueHealthChecks(ctx context.Context, checkedBefore time.Time, limit int) ([]model.URL, error) {
+	query := fmt.Sprintf(dueHealthChecksQuery, "?", "?", "?")
+
+	rows, err := s.db.QueryContext(ctx, query, time.Now(), checkedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links to check: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []model.URL
+	for rows.Next() {
+		var url model.URL
+		err := rows.Scan(
+			&url.ID,
+			&url.OriginalURL,
+			&url.ShortCode,
+			&url.CreatedAt,
+			&url.ExpiresAt,
+			&url.ClickCount,
+			&url.LastStatus,
+			&url.LastCheckedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get links to check: %w", err)
+		}
+		urls = append(urls, url)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get links to check: %w", err)
+	}
+
+	return urls, nil
+}
+
+func (s *SQLiteStorage) RecordHealth(ctx context.Context, code string, status int, checkedAt time.Time) error {
+	query := `
+		UPDATE urls
+		SET last_status = ?, last_checked_at = ?
+		WHERE ` + s.codeMatch("?")
+
+	result, err := s.db.ExecContext(ctx, query, status, checkedAt, code)
+	if err != nil {
+		return fmt.Errorf("failed to record link health: %w", err)
+	}
+
+	return checkRowsAffected(result)
+}
+
+func (s *SQLiteStorage) ListByHealth(ctx context.Context, health string, limit int) ([]model.Stats, error) {
+	condition, err := healthCondition(health)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(listByHealthQuery, condition, "?"), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	defer rows.Close()
+
+	var list []model.Stats
+	for rows.Next() {
+		var stats model.Stats
+		err := rows.Scan(
+			&stats.ShortCode,
+			&stats.OriginalURL,
+			&stats.ClickCount,
+			&stats.CreatedAt,
+			&stats.ExpiresAt,
+			&stats.RedirectType,
+			&stats.Interstitial,
+			&stats.PasswordProtected,
+			&stats.MaxClicks,
+			&stats.LastStatus,
+			&stats.LastCheckedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list links: %w", err)
+		}
+		list = append(list, stats)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	return list, nil
+}
+
+// codeMatch условие сравнения short_code с параметром с учетом режима регистра
+func (s *SQLiteStorage) codeMatch(param string) string {
+	if s.foldCase {
+		return "lower(short_code) = lower(" + param + ")"
+	}
+	return "short_code = " + param
+}
+
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// checkRowsAffected возвращает ErrNotFound, если запрос не затронул ни одной строки
+func checkRowsAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
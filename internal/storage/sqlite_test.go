@@ -0,0 +1,212 @@
+//go:build cgo
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/migrate"
+	"github.com/dmitrycr/ShortUrl/internal/model"
+)
+
+func init() {
+	testStores["SQLite"] = func(t *testing.T) Storage {
+		s, err := NewSQLiteStorage(context.Background(), filepath.Join(t.TempDir(), "urls.db"))
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		return s
+	}
+}
+
+func TestOpen_SQLite(t *testing.T) {
+	s, err := Open(context.Background(), "file://"+filepath.Join(t.TempDir(), "urls.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+
+	if _, ok := s.(*SQLiteStorage); !ok {
+		t.Errorf("Expected *SQLiteStorage, got %T", s)
+	}
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	ctx := context.Background()
+
+	storage, err := NewSQLiteStorage(ctx, filepath.Join(t.TempDir(), "urls.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer storage.Close()
+
+	t.Run("Save and Get", func(t *testing.T) {
+		url := &model.URL{
+			OriginalURL:  "https://example.com/test",
+			ShortCode:    "test123",
+			CreatedAt:    time.Now(),
+			RedirectType: 308,
+			Interstitial: true,
+			PasswordHash: "pbkdf2-sha256$1$c2FsdA$a2V5",
+		}
+
+		if err := storage.Save(ctx, url); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if url.ID == 0 {
+			t.Error("Expected ID to be assigned")
+		}
+
+		retrieved, err := storage.GetByShortCode(ctx, "test123")
+		if err != nil {
+			t.Fatalf("GetByShortCode failed: %v", err)
+		}
+
+		if retrieved.OriginalURL != url.OriginalURL {
+			t.Errorf("Expected %s, got %s", url.OriginalURL, retrieved.OriginalURL)
+		}
+		if retrieved.RedirectType != 308 || !retrieved.Interstitial {
+			t.Errorf("Expected redirect type 308 with interstitial, got %d, %v", retrieved.RedirectType, retrieved.Interstitial)
+		}
+		if retrieved.PasswordHash != url.PasswordHash {
+			t.Errorf("Expected password hash %q, got %q", url.PasswordHash, retrieved.PasswordHash)
+		}
+
+		stats, err := storage.GetStats(ctx, "test123")
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+		if !stats.PasswordProtected {
+			t.Error("Expected stats to report password protection")
+		}
+	})
+
+	t.Run("Duplicate ShortCode", func(t *testing.T) {
+		url := &model.URL{
+			OriginalURL: "https://example.com/another",
+			ShortCode:   "test123",
+			CreatedAt:   time.Now(),
+		}
+
+		err := storage.Save(ctx, url)
+		if err != ErrDuplicateCode {
+			t.Errorf("Expected ErrDuplicateCode, got %v", err)
+		}
+	})
+
+	t.Run("IncrementClicks", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			if err := storage.IncrementClicks(ctx, "test123"); err != nil {
+				t.Fatalf("IncrementClicks failed: %v", err)
+			}
+		}
+
+		stats, err := storage.GetStats(ctx, "test123")
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+
+		if stats.ClickCount != 5 {
+			t.Errorf("Expected 5 clicks, got %d", stats.ClickCount)
+		}
+	})
+
+	t.Run("Expired URL", func(t *testing.T) {
+		expiredTime := time.Now().Add(-1 * time.Hour)
+		url := &model.URL{
+			OriginalURL: "https://example.com/expired",
+			ShortCode:   "expired123",
+			CreatedAt:   time.Now(),
+			ExpiresAt:   &expiredTime,
+		}
+
+		if err := storage.Save(ctx, url); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		_, err := storage.GetByShortCode(ctx, "expired123")
+		if err != ErrExpired {
+			t.Errorf("Expected ErrExpired, got %v", err)
+		}
+	})
+
+	t.Run("TakenCodes", func(t *testing.T) {
+		taken, err := storage.TakenCodes(ctx, []string{"test123", "free123", "expired123"})
+		if err != nil {
+			t.Fatalf("TakenCodes failed: %v", err)
+		}
+
+		// Истекшая ссылка по-прежнему занимает код
+		if len(taken) != 2 {
+			t.Errorf("Expected 2 taken codes, got %v", taken)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := storage.Delete(ctx, "test123"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		if err := storage.Delete(ctx, "test123"); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		if _, err := storage.GetByShortCode(ctx, "test123"); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestSQLiteStorage_CaseInsensitiveMigration(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := NewSQLiteStorage(ctx, filepath.Join(t.TempDir(), "urls.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer s.Close()
+
+	for _, code := range []string{"AbC123", "abc123", "Other1"} {
+		if err := s.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: code}); err != nil {
+			t.Fatalf("Save(%q) failed: %v", code, err)
+		}
+	}
+
+	enabled, err := s.Migrator(migrate.WithFeature(FeatureCaseInsensitiveCodes, true))
+	if err != nil {
+		t.Fatalf("Migrator failed: %v", err)
+	}
+
+	// Миграция сама прерывается со списком конфликтов
+	_, err = enabled.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "case conflicts: 1 groups (AbC123/abc123)") {
+		t.Fatalf("Expected case conflict report, got %v", err)
+	}
+
+	if err := s.Delete(ctx, "abc123"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if applied, err := enabled.Up(ctx); err != nil || len(applied) != 1 {
+		t.Fatalf("Expected case-insensitive migration to be applied, got %v, %v", applied, err)
+	}
+	if err := s.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: "OTHER1"}); !errors.Is(err, ErrDuplicateCode) {
+		t.Errorf("Expected ErrDuplicateCode, got %v", err)
+	}
+
+	// Выключение режима откатывает миграцию
+	disabled, err := s.Migrator(migrate.WithFeature(FeatureCaseInsensitiveCodes, false))
+	if err != nil {
+		t.Fatalf("Migrator failed: %v", err)
+	}
+	if reverted, err := disabled.RevertDisabled(ctx); err != nil || len(reverted) != 1 {
+		t.Fatalf("Expected case-insensitive migration to be reverted, got %v, %v", reverted, err)
+	}
+	if err := s.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: "OTHER1"}); err != nil {
+		t.Errorf("Save(OTHER1) after revert failed: %v", err)
+	}
+}
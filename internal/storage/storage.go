@@ -3,14 +3,25 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/dmitrycr/ShortUrl/internal/migrate"
 	"github.com/dmitrycr/ShortUrl/internal/model"
 )
 
 var (
-	ErrNotFound      = errors.New("url not found")
-	ErrDuplicateCode = errors.New("short code already exists")
-	ErrExpired       = errors.New("url has expired")
+	ErrNotFound         = errors.New("url not found")
+	ErrDuplicateCode    = errors.New("short code already exists")
+	ErrExpired          = errors.New("url has expired")
+	ErrUnknownScheme    = errors.New("unknown storage scheme")
+	ErrCaseConflict     = errors.New("short codes differ only in case")
+	ErrCaseIndexMissing = errors.New("case-insensitive code index is missing")
+	ErrUnknownHealth    = errors.New("unknown link health")
+	ErrClicksExhausted  = errors.New("click limit reached")
 )
 
 type Storage interface {
@@ -21,3 +32,259 @@ type Storage interface {
 	Delete(ctx context.Context, code string) error
 	Close() error
 }
+
+// FeatureCaseInsensitiveCodes функция миграций с индексом по lower(short_code)
+const FeatureCaseInsensitiveCodes = "case_insensitive_codes"
+
+// Migratable реализуется хранилищами, схема которых управляется миграциями
+type Migratable interface {
+	Migrator(opts ...migrate.Option) (*migrate.Migrator, error)
+}
+
+// Sequencer реализуется хранилищами, выдающими монотонные ID для генерации кодов
+type Sequencer interface {
+	NextID(ctx context.Context) (int64, error)
+}
+
+// CodeReserver реализуется хранилищами, поддерживающими пул заранее сгенерированных кодов
+type CodeReserver interface {
+	// ReserveCodes резервирует свободные коды и возвращает те, что удалось занять
+	// Коды, уже занятые ссылкой или другим резервом, пропускаются
+	ReserveCodes(ctx context.Context, codes []string) ([]string, error)
+
+	// ReleaseCodes снимает резерв с кодов (выданных или возвращаемых в общий доступ)
+	ReleaseCodes(ctx context.Context, codes []string) error
+
+	// ReclaimCodes снимает резерв, поставленный раньше olderThan, — его оставили
+	// экземпляры, упавшие до выдачи или возврата кодов. Возвращает число кодов
+	ReclaimCodes(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+// CodeChecker реализуется хранилищами, умеющими проверить занятость кодов одним запросом
+type CodeChecker interface {
+	// TakenCodes возвращает коды из списка, уже занятые ссылками (в том числе истекшими)
+	TakenCodes(ctx context.Context, codes []string) ([]string, error)
+}
+
+// CodeCounter реализуется хранилищами, умеющими посчитать коды по длине
+type CodeCounter interface {
+	// CountCodesByLength возвращает число ссылок (в том числе истекших) для каждой длины кода
+	CountCodesByLength(ctx context.Context) (map[int]int64, error)
+}
+
+// CaseInsensitiveCodes реализуется хранилищами, умеющими сравнивать коды без учета регистра
+type CaseInsensitiveCodes interface {
+	// CodeCaseConflicts возвращает группы существующих кодов, совпадающих без учета регистра
+	CodeCaseConflicts(ctx context.Context) ([][]string, error)
+
+	// EnableCaseInsensitiveCodes включает сравнение без учета регистра для поиска
+	// и уникальности кодов. Если есть конфликты, возвращает ErrCaseConflict и ничего не меняет
+	// Хранилищам с миграциями нужна миграция FeatureCaseInsensitiveCodes: без нее
+	// и без автоматических миграций возвращается ErrCaseIndexMissing
+	EnableCaseInsensitiveCodes(ctx context.Context) error
+}
+
+// HealthTracker реализуется хранилищами, хранящими результат проверки целевых URL
+type HealthTracker interface {
+	// DueHealthChecks возвращает действующие ссылки, не проверявшиеся с checkedBefore:
+	// сначала непроверенные, затем самые давние; не более limit
+	DueHealthChecks(ctx context.Context, checkedBefore time.Time, limit int) ([]model.URL, error)
+
+	// RecordHealth сохраняет статус ответа (0 — запрос не удался) и время проверки
+	RecordHealth(ctx context.Context, code string, status int, checkedAt time.Time) error
+
+	// ListByHealth возвращает ссылки в состоянии health (model.HealthBroken, ...)
+	// в порядке создания; не более limit. Ссылки с паролем и лимитом переходов
+	// не возвращаются: их адрес и код не раскрываются в отчетах
+	ListByHealth(ctx context.Context, health string, limit int) ([]model.Stats, error)
+}
+
+// ClickLimiter реализуется хранилищами, учитывающими переходы по ссылкам с лимитом
+type ClickLimiter interface {
+	// ConsumeClick увеличивает счетчик переходов, если лимит max_clicks не исчерпан.
+	// Проверка и увеличение — одна операция: параллельные переходы не превышают лимит.
+	// Исчерпанный лимит — ErrClicksExhausted, истекшая ссылка — ErrExpired
+	ConsumeClick(ctx context.Context, code string) error
+}
+
+// Opener создает хранилище по строке подключения
+type Opener func(ctx context.Context, dsn string) (Storage, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Opener)
+)
+
+// Register регистрирует хранилище для схемы DSN (postgres, memory, file, ...)
+// Вызывается из init() реализаций; повторная регистрация схемы — ошибка программиста
+func Register(scheme string, opener Opener) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if _, exists := registry[scheme]; exists {
+		panic("storage: Register called twice for scheme " + scheme)
+	}
+	registry[scheme] = opener
+}
+
+// Open создает хранилище, выбирая реализацию по схеме DSN
+func Open(ctx context.Context, dsn string) (Storage, error) {
+	scheme := Scheme(dsn)
+
+	registryMu.RLock()
+	opener, ok := registry[scheme]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (supported: %s)",
+			ErrUnknownScheme, scheme, strings.Join(Schemes(), ", "))
+	}
+
+	return opener(ctx, dsn)
+}
+
+// Scheme возвращает схему DSN в нижнем регистре
+// Удобно для логирования без раскрытия учетных данных
+func Scheme(dsn string) string {
+	scheme, _, found := strings.Cut(dsn, ":")
+	if !found {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
+
+// Schemes возвращает отсортированный список зарегистрированных схем
+func Schemes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	schemes := make([]string, 0, len(registry))
+	for scheme := range registry {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// countCodesByLengthQuery число ссылок по длине кода — одинаков для Postgres и SQLite
+const countCodesByLengthQuery = `
+	SELECT length(short_code), count(*) FROM urls
+	GROUP BY length(short_code)
+`
+
+// Запросы режима без учета регистра — одинаковы для Postgres и SQLite
+const (
+	caseConflictsQuery = `
+		SELECT short_code FROM urls
+		WHERE lower(short_code) IN (
+			SELECT lower(short_code) FROM urls
+			GROUP BY lower(short_code)
+			HAVING count(*) > 1
+		)
+	`
+
+	// lowerCodeIndex уникальный индекс по lower(short_code) из миграции
+	// FeatureCaseInsensitiveCodes: уникальность без учета регистра и быстрый поиск
+	lowerCodeIndex = "idx_urls_short_code_lower"
+)
+
+// Запросы проверки целевых URL — одинаковы для Postgres и SQLite,
+// отличаются только плейсхолдеры
+const (
+	healthColumns = "COALESCE(last_status, 0), last_checked_at"
+
+	dueHealthChecksQuery = `
+		SELECT id, original_url, short_code, created_at, expires_at, click_count, ` + healthColumns + `
+		FROM urls
+		WHERE (expires_at IS NULL OR expires_at > %[1]s)
+		  AND (last_checked_at IS NULL OR last_checked_at < %[2]s)
+		ORDER BY last_checked_at IS NOT NULL, last_checked_at, id
+		LIMIT %[3]s
+	`
+
+	listByHealthQuery = `
+		SELECT short_code, original_url, click_count, created_at, expires_at, redirect_type, interstitial, password_hash <> '', max_clicks, ` + healthColumns + `
+		FROM urls
+		WHERE %[1]s AND password_hash = '' AND max_clicks = 0
+		ORDER BY id
+		LIMIT %[2]s
+	`
+)
+
+// consumeClickQuery увеличивает счетчик действующей ссылки с неисчерпанным лимитом;
+// одинаков для Postgres и SQLite, отличаются только плейсхолдеры
+const consumeClickQuery = `
+	UPDATE urls
+	SET click_count = click_count + 1
+	WHERE %[1]s
+	  AND (max_clicks = 0 OR click_count < max_clicks)
+	  AND (expires_at IS NULL OR expires_at > %[2]s)
+`
+
+// clickRefusal объясняет, почему ConsumeClick не обновил ни одной строки
+func clickRefusal(ctx context.Context, s Storage, code string) error {
+	if _, err := s.GetByShortCode(ctx, code); err != nil {
+		return err
+	}
+	return ErrClicksExhausted
+}
+
+// healthCondition SQL-условие для состояния ссылки; повторяет model.HealthOf
+func healthCondition(health string) (string, error) {
+	switch health {
+	case model.HealthUnchecked:
+		return "last_checked_at IS NULL", nil
+	case model.HealthBroken:
+		return "last_checked_at IS NOT NULL AND (last_status = 0 OR last_status IN (404, 410) OR last_status >= 500)", nil
+	case model.HealthOK:
+		return "last_checked_at IS NOT NULL AND last_status <> 0 AND last_status NOT IN (404, 410) AND last_status < 500", nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownHealth, health)
+	}
+}
+
+// maxReportedConflicts сколько групп конфликтов перечислять в тексте ошибки
+const maxReportedConflicts = 5
+
+// caseConflictError описывает конфликты регистра для ErrCaseConflict
+func caseConflictError(conflicts [][]string) error {
+	examples := make([]string, 0, maxReportedConflicts)
+	for i, group := range conflicts {
+		if i == maxReportedConflicts {
+			break
+		}
+		examples = append(examples, strings.Join(group, "/"))
+	}
+
+	return fmt.Errorf("%w: %d groups (%s); rename them before enabling case-insensitive codes",
+		ErrCaseConflict, len(conflicts), strings.Join(examples, ", "))
+}
+
+// groupCaseConflicts группирует коды, совпадающие без учета регистра
+// Возвращает только группы из двух и более кодов, упорядоченные по ключу
+func groupCaseConflicts(codes []string) [][]string {
+	groups := make(map[string][]string)
+	for _, code := range codes {
+		key := strings.ToLower(code)
+		groups[key] = append(groups[key], code)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key, group := range groups {
+		if len(group) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	conflicts := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		group := groups[key]
+		sort.Strings(group)
+		conflicts = append(conflicts, group)
+	}
+
+	return conflicts
+}
@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/internal/model"
+)
+
+// testStores хранилища для общих тестов; SQLite добавляется в сборках с cgo
+var testStores = map[string]func(t *testing.T) Storage{
+	"Memory": func(t *testing.T) Storage {
+		return NewInMemoryStorage()
+	},
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Memory", func(t *testing.T) {
+		s, err := Open(ctx, "memory://")
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer s.Close()
+
+		if _, ok := s.(*InMemoryStorage); !ok {
+			t.Errorf("Expected *InMemoryStorage, got %T", s)
+		}
+	})
+
+	t.Run("Unknown scheme", func(t *testing.T) {
+		_, err := Open(ctx, "mongodb://localhost")
+		if !errors.Is(err, ErrUnknownScheme) {
+			t.Errorf("Expected ErrUnknownScheme, got %v", err)
+		}
+	})
+}
+
+func TestEnableCaseInsensitiveCodes(t *testing.T) {
+	ctx := context.Background()
+
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			defer s.Close()
+			folder := s.(CaseInsensitiveCodes)
+
+			for _, code := range []string{"AbC123", "abc123", "Other1"} {
+				if err := s.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: code}); err != nil {
+					t.Fatalf("Save(%q) failed: %v", code, err)
+				}
+			}
+
+			// Конфликт регистра не дает включить режим
+			if err := folder.EnableCaseInsensitiveCodes(ctx); !errors.Is(err, ErrCaseConflict) {
+				t.Fatalf("Expected ErrCaseConflict, got %v", err)
+			}
+			conflicts, err := folder.CodeCaseConflicts(ctx)
+			if err != nil || len(conflicts) != 1 || len(conflicts[0]) != 2 {
+				t.Fatalf("Expected one conflict group, got %v, %v", conflicts, err)
+			}
+
+			if err := s.Delete(ctx, "abc123"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if err := folder.EnableCaseInsensitiveCodes(ctx); err != nil {
+				t.Fatalf("EnableCaseInsensitiveCodes failed: %v", err)
+			}
+
+			// Старый код в смешанном регистре находится в любом регистре
+			if _, err := s.GetByShortCode(ctx, "abc123"); err != nil {
+				t.Errorf("GetByShortCode(abc123) failed: %v", err)
+			}
+			if err := s.Save(ctx, &model.URL{OriginalURL: "https://example.com", ShortCode: "OTHER1"}); !errors.Is(err, ErrDuplicateCode) {
+				t.Errorf("Expected ErrDuplicateCode, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHealthTracker(t *testing.T) {
+	ctx := context.Background()
+
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			defer s.Close()
+			tracker := s.(HealthTracker)
+
+			now := time.Now()
+			expired := now.Add(-time.Hour)
+			for _, url := range []*model.URL{
+				{OriginalURL: "https://example.com/ok", ShortCode: "ok1234", CreatedAt: now},
+				{OriginalURL: "https://example.com/gone", ShortCode: "gone12", CreatedAt: now},
+				{OriginalURL: "https://example.com/old", ShortCode: "old123", CreatedAt: now, ExpiresAt: &expired},
+				{OriginalURL: "https://example.com/secret", ShortCode: "secret", CreatedAt: now, PasswordHash: "pbkdf2-sha256$1$c2FsdA$a2V5"},
+				{OriginalURL: "https://example.com/invite", ShortCode: "invite", CreatedAt: now, MaxClicks: 3},
+			} {
+				if err := s.Save(ctx, url); err != nil {
+					t.Fatalf("Save(%q) failed: %v", url.ShortCode, err)
+				}
+			}
+
+			// Истекшие ссылки не проверяются
+			due, err := tracker.DueHealthChecks(ctx, now, 10)
+			if err != nil || len(due) != 4 {
+				t.Fatalf("Expected 4 links to check, got %d, %v", len(due), err)
+			}
+
+			if err := tracker.RecordHealth(ctx, "ok1234", 200, now); err != nil {
+				t.Fatalf("RecordHealth failed: %v", err)
+			}
+			if err := tracker.RecordHealth(ctx, "gone12", 404, now); err != nil {
+				t.Fatalf("RecordHealth failed: %v", err)
+			}
+			// Защищенные ссылки проверяются, но в списки не попадают
+			for _, code := range []string{"secret", "invite"} {
+				if err := tracker.RecordHealth(ctx, code, 404, now); err != nil {
+					t.Fatalf("RecordHealth failed: %v", err)
+				}
+			}
+			if err := tracker.RecordHealth(ctx, "missing", 200, now); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound, got %v", err)
+			}
+
+			if due, _ := tracker.DueHealthChecks(ctx, now.Add(-time.Minute), 10); len(due) != 0 {
+				t.Errorf("Expected no links to check, got %d", len(due))
+			}
+
+			broken, err := tracker.ListByHealth(ctx, model.HealthBroken, 10)
+			if err != nil || len(broken) != 1 || broken[0].ShortCode != "gone12" || broken[0].LastStatus != 404 {
+				t.Errorf("Expected gone12 to be broken, got %+v, %v", broken, err)
+			}
+			if unchecked, _ := tracker.ListByHealth(ctx, model.HealthUnchecked, 10); len(unchecked) != 1 {
+				t.Errorf("Expected 1 unchecked link, got %d", len(unchecked))
+			}
+			if _, err := tracker.ListByHealth(ctx, "dead", 10); !errors.Is(err, ErrUnknownHealth) {
+				t.Errorf("Expected ErrUnknownHealth, got %v", err)
+			}
+
+			stats, err := s.GetStats(ctx, "ok1234")
+			if err != nil || stats.LastStatus != 200 || stats.LastCheckedAt == nil {
+				t.Errorf("Expected health in stats, got %+v, %v", stats, err)
+			}
+		})
+	}
+}
+
+func TestClickLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			defer s.Close()
+			limiter := s.(ClickLimiter)
+
+			now := time.Now()
+			expired := now.Add(-time.Hour)
+			for _, url := range []*model.URL{
+				{OriginalURL: "https://example.com/invite", ShortCode: "invite", CreatedAt: now, MaxClicks: 3},
+				{OriginalURL: "https://example.com/open", ShortCode: "open12", CreatedAt: now},
+				{OriginalURL: "https://example.com/old", ShortCode: "old123", CreatedAt: now, ExpiresAt: &expired, MaxClicks: 3},
+			} {
+				if err := s.Save(ctx, url); err != nil {
+					t.Fatalf("Save(%q) failed: %v", url.ShortCode, err)
+				}
+			}
+
+			// Параллельные переходы не превышают лимит
+			var (
+				wg        sync.WaitGroup
+				mu        sync.Mutex
+				consumed  int
+				exhausted int
+			)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := limiter.ConsumeClick(ctx, "invite")
+
+					mu.Lock()
+					defer mu.Unlock()
+					switch {
+					case err == nil:
+						consumed++
+					case errors.Is(err, ErrClicksExhausted):
+						exhausted++
+					default:
+						t.Errorf("ConsumeClick failed: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if consumed != 3 || exhausted != 17 {
+				t.Errorf("Expected 3 consumed and 17 exhausted, got %d and %d", consumed, exhausted)
+			}
+
+			stats, err := s.GetStats(ctx, "invite")
+			if err != nil {
+				t.Fatalf("GetStats failed: %v", err)
+			}
+			if stats.ClickCount != 3 || stats.MaxClicks != 3 {
+				t.Errorf("Expected 3 of 3 clicks, got %d of %d", stats.ClickCount, stats.MaxClicks)
+			}
+
+			// Без лимита — обычный счетчик
+			for i := 0; i < 5; i++ {
+				if err := limiter.ConsumeClick(ctx, "open12"); err != nil {
+					t.Fatalf("ConsumeClick(unlimited) failed: %v", err)
+				}
+			}
+
+			if err := limiter.ConsumeClick(ctx, "old123"); !errors.Is(err, ErrExpired) {
+				t.Errorf("Expected ErrExpired, got %v", err)
+			}
+			if err := limiter.ConsumeClick(ctx, "nope12"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReclaimCodes(t *testing.T) {
+	ctx := context.Background()
+
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			defer s.Close()
+			reserver := s.(CodeReserver)
+
+			if _, err := reserver.ReserveCodes(ctx, []string{"stale1", "stale2"}); err != nil {
+				t.Fatalf("ReserveCodes failed: %v", err)
+			}
+			cutoff := time.Now()
+			time.Sleep(10 * time.Millisecond)
+			if _, err := reserver.ReserveCodes(ctx, []string{"fresh1"}); err != nil {
+				t.Fatalf("ReserveCodes failed: %v", err)
+			}
+
+			reclaimed, err := reserver.ReclaimCodes(ctx, cutoff)
+			if err != nil {
+				t.Fatalf("ReclaimCodes failed: %v", err)
+			}
+			if reclaimed != 2 {
+				t.Errorf("Expected 2 reclaimed codes, got %d", reclaimed)
+			}
+
+			// Снятый резерв можно поставить снова, свежий — нет
+			got, err := reserver.ReserveCodes(ctx, []string{"stale1", "stale2", "fresh1"})
+			if err != nil {
+				t.Fatalf("ReserveCodes failed: %v", err)
+			}
+			if len(got) != 2 || got[0] != "stale1" || got[1] != "stale2" {
+				t.Errorf("Expected stale codes to be reservable again, got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package validator
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// blockedPrefixes диапазоны, куда нельзя перенаправлять: частные сети,
+// loopback, link-local (в том числе метаданные облаков 169.254.169.254),
+// CGNAT, документационные и зарезервированные блоки
+var blockedPrefixes = mustParsePrefixes(
+	// IPv4
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.88.99.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+
+	// IPv6
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"64:ff9b:1::/48",
+	"100::/64",
+	"2001::/23",
+	"2001:db8::/32",
+	"2002::/16",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// isBlockedAddr сообщает, ведет ли адрес во внутреннюю или зарезервированную сеть
+func isBlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DialControl для net.Dialer.Control: отклоняет подключение к внутренним
+// и зарезервированным адресам. Проверяется адрес, к которому идет соединение,
+// поэтому смена DNS-записи после проверки URL (DNS rebinding) не помогает
+func DialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+	if isBlockedAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// NewSafeTransport возвращает транспорт для запросов к целевым URL,
+// который не подключается к внутренним адресам (см. DialControl)
+// Прокси отключен: через него проверялся бы адрес прокси, а не цели
+func NewSafeTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   DialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// parseHostAddr разбирает хост как IP-литерал
+// Кроме канонической записи понимает формы, которые принимают inet_aton
+// и браузеры: 2130706433, 0x7f.1, 0177.0.0.1 — иначе через них можно обойти проверку
+func parseHostAddr(host string) (netip.Addr, bool) {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr, true
+	}
+
+	return parseLegacyIPv4(host)
+}
+
+// parseLegacyIPv4 разбирает IPv4 в стиле inet_aton: 1–4 части,
+// каждая десятичная, восьмеричная (0...) или шестнадцатеричная (0x...)
+func parseLegacyIPv4(host string) (netip.Addr, bool) {
+	parts := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if len(parts) == 0 || len(parts) > 4 {
+		return netip.Addr{}, false
+	}
+
+	values := make([]uint64, len(parts))
+	for i, part := range parts {
+		value, ok := parseIPv4Part(part)
+		if !ok {
+			return netip.Addr{}, false
+		}
+		values[i] = value
+	}
+
+	// Последняя часть заполняет оставшиеся байты: a.b.c.d, a.b.cd, a.bcd, abcd
+	last := values[len(values)-1]
+	if last >= 1<<(8*(5-len(values))) {
+		return netip.Addr{}, false
+	}
+
+	ip := last
+	for i, value := range values[:len(values)-1] {
+		if value > 0xff {
+			return netip.Addr{}, false
+		}
+		ip |= value << (8 * (3 - i))
+	}
+
+	return netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)}), true
+}
+
+func parseIPv4Part(part string) (uint64, bool) {
+	if part == "" {
+		return 0, false
+	}
+
+	base := 10
+	switch {
+	case strings.HasPrefix(part, "0x") || strings.HasPrefix(part, "0X"):
+		base = 16
+		part = part[2:]
+		if part == "" {
+			return 0, true
+		}
+	case len(part) > 1 && part[0] == '0':
+		base = 8
+		part = part[1:]
+	}
+
+	value, err := strconv.ParseUint(part, base, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
+
+// isLocalHostname сообщает, что имя всегда указывает на локальную машину
+func isLocalHostname(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+func mustParsePrefixes(prefixes ...string) []netip.Prefix {
+	parsed := make([]netip.Prefix, len(prefixes))
+	for i, prefix := range prefixes {
+		parsed[i] = netip.MustParsePrefix(prefix)
+	}
+	return parsed
+}
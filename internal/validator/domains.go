@@ -0,0 +1,314 @@
+package validator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrDomainDenied     = errors.New("URL domain is denied by policy")
+	ErrDomainNotAllowed = errors.New("URL domain is not in the allowlist")
+	ErrInvalidRule      = errors.New("invalid domain rule")
+)
+
+// DomainChecker проверяет домен целевого URL; реализуется DomainPolicy и DomainPolicyFile
+type DomainChecker interface {
+	CheckDomain(host string) error
+}
+
+// domainMatch вид правила домена
+type domainMatch int
+
+const (
+	matchExact      domainMatch = iota // example.com — только сам домен
+	matchSubdomains                    // *.example.com — только поддомены
+	matchSuffix                        // .example.com — домен и все поддомены
+)
+
+type domainRule struct {
+	domain string
+	match  domainMatch
+}
+
+func (r domainRule) matches(host string) bool {
+	switch r.match {
+	case matchExact:
+		return host == r.domain
+	case matchSubdomains:
+		return strings.HasSuffix(host, "."+r.domain)
+	default:
+		return host == r.domain || strings.HasSuffix(host, "."+r.domain)
+	}
+}
+
+// DomainSet набор доменов, заданных шаблонами:
+// example.com (только сам домен), *.example.com (только поддомены)
+// или .example.com (домен и все поддомены)
+type DomainSet struct {
+	rules []domainRule
+}
+
+// NewDomainSet создает набор из шаблонов
+func NewDomainSet(patterns ...string) (*DomainSet, error) {
+	set := &DomainSet{rules: make([]domainRule, 0, len(patterns))}
+
+	for _, pattern := range patterns {
+		rule, err := parseDomainRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		set.rules = append(set.rules, rule)
+	}
+
+	return set, nil
+}
+
+// Len возвращает количество шаблонов
+func (s *DomainSet) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rules)
+}
+
+// Contains сообщает, подходит ли хост под один из шаблонов
+func (s *DomainSet) Contains(host string) bool {
+	if s == nil {
+		return false
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, rule := range s.rules {
+		if rule.matches(host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DomainPolicy правила доменов целевых URL
+//
+// Запрет сильнее разрешения: домен из deny отклоняется, даже если он есть в allow.
+// Если задано хотя бы одно правило allow, домены вне списка тоже отклоняются
+type DomainPolicy struct {
+	allow *DomainSet
+	deny  *DomainSet
+}
+
+// NewDomainPolicy создает политику из списков разрешенных и запрещенных шаблонов
+// (формат шаблонов см. DomainSet)
+func NewDomainPolicy(allow, deny []string) (*DomainPolicy, error) {
+	allowSet, err := NewDomainSet(allow...)
+	if err != nil {
+		return nil, err
+	}
+
+	denySet, err := NewDomainSet(deny...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DomainPolicy{allow: allowSet, deny: denySet}, nil
+}
+
+// ParseDomainPolicy читает политику: одно правило на строку, # — комментарий
+//
+//	allow example.com
+//	allow *.corp.example.com
+//	deny  .abuse.example
+func ParseDomainPolicy(r io.Reader) (*DomainPolicy, error) {
+	var allow, deny []string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w on line %d: expected \"allow|deny <domain>\"", ErrInvalidRule, n)
+		}
+
+		switch strings.ToLower(fields[0]) {
+		case "allow":
+			allow = append(allow, fields[1])
+		case "deny":
+			deny = append(deny, fields[1])
+		default:
+			return nil, fmt.Errorf("%w on line %d: unknown action %q", ErrInvalidRule, n, fields[0])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domain policy: %w", err)
+	}
+
+	return NewDomainPolicy(allow, deny)
+}
+
+// LoadDomainPolicy читает политику из файла (формат см. ParseDomainPolicy)
+func LoadDomainPolicy(path string) (*DomainPolicy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open domain policy: %w", err)
+	}
+	defer file.Close()
+
+	policy, err := ParseDomainPolicy(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// parseDomainRule разбирает шаблон и приводит домен к виду после NormalizeURL
+func parseDomainRule(pattern string) (domainRule, error) {
+	rule := domainRule{match: matchExact}
+	domain := strings.TrimSpace(pattern)
+
+	switch {
+	case strings.HasPrefix(domain, "*."):
+		rule.match = matchSubdomains
+		domain = domain[2:]
+	case strings.HasPrefix(domain, "."):
+		rule.match = matchSuffix
+		domain = domain[1:]
+	}
+
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		domain = ascii
+	}
+
+	if domain == "" || strings.ContainsAny(domain, "*/:@ ") {
+		return domainRule{}, fmt.Errorf("%w: %q", ErrInvalidRule, pattern)
+	}
+
+	rule.domain = domain
+	return rule, nil
+}
+
+// Len возвращает количество правил
+func (p *DomainPolicy) Len() int {
+	if p == nil {
+		return 0
+	}
+	return p.allow.Len() + p.deny.Len()
+}
+
+// CheckDomain возвращает ErrDomainDenied или ErrDomainNotAllowed,
+// если хост не проходит политику. Пустая политика пропускает все
+func (p *DomainPolicy) CheckDomain(host string) error {
+	if p == nil {
+		return nil
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if p.deny.Contains(host) {
+		return fmt.Errorf("%w: %s", ErrDomainDenied, host)
+	}
+
+	if p.allow.Len() > 0 && !p.allow.Contains(host) {
+		return fmt.Errorf("%w: %s", ErrDomainNotAllowed, host)
+	}
+
+	return nil
+}
+
+// DomainPolicyFile политика из файла, которую можно перечитать без перезапуска
+// При ошибке чтения остается действовать прежняя политика
+type DomainPolicyFile struct {
+	path   string
+	policy atomic.Pointer[DomainPolicy]
+
+	mu      sync.Mutex // сериализует перечитывание
+	modTime time.Time
+	size    int64
+}
+
+// OpenDomainPolicy загружает политику из файла
+func OpenDomainPolicy(path string) (*DomainPolicyFile, error) {
+	f := &DomainPolicyFile{path: path}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path возвращает путь к файлу политики
+func (f *DomainPolicyFile) Path() string {
+	return f.path
+}
+
+// Policy возвращает действующую политику
+func (f *DomainPolicyFile) Policy() *DomainPolicy {
+	return f.policy.Load()
+}
+
+// Len возвращает количество правил действующей политики
+func (f *DomainPolicyFile) Len() int {
+	return f.policy.Load().Len()
+}
+
+// CheckDomain проверяет хост по действующей политике
+func (f *DomainPolicyFile) CheckDomain(host string) error {
+	return f.policy.Load().CheckDomain(host)
+}
+
+// Reload перечитывает файл (например, по SIGHUP)
+func (f *DomainPolicyFile) Reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to open domain policy: %w", err)
+	}
+
+	return f.load(info)
+}
+
+// ReloadIfChanged перечитывает файл, если изменились время модификации или размер
+func (f *DomainPolicyFile) ReloadIfChanged() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open domain policy: %w", err)
+	}
+
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return false, nil
+	}
+
+	if err := f.load(info); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (f *DomainPolicyFile) load(info os.FileInfo) error {
+	// Версия запоминается и при ошибке, чтобы не повторять ее на каждой проверке
+	f.modTime, f.size = info.ModTime(), info.Size()
+
+	policy, err := LoadDomainPolicy(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.policy.Store(policy)
+	return nil
+}
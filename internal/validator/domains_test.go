@@ -0,0 +1,108 @@
+package validator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDomainPolicy(t *testing.T) {
+	policy, err := ParseDomainPolicy(strings.NewReader(`
+# Домены клиента
+allow example.com
+allow *.corp.example.com
+allow .пример.рф
+
+deny  bad.corp.example.com  # скомпрометирован
+`))
+	if err != nil {
+		t.Fatalf("ParseDomainPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want error
+	}{
+		{"example.com", nil},
+		{"EXAMPLE.com.", nil},
+		{"www.example.com", ErrDomainNotAllowed},
+		{"app.corp.example.com", nil},
+		{"corp.example.com", ErrDomainNotAllowed},
+		{"bad.corp.example.com", ErrDomainDenied},
+		{"xn--e1afmkfd.xn--p1ai", nil},
+		{"shop.xn--e1afmkfd.xn--p1ai", nil},
+		{"notexample.com", ErrDomainNotAllowed},
+	}
+
+	for _, tt := range tests {
+		if err := policy.CheckDomain(tt.host); !errors.Is(err, tt.want) {
+			t.Errorf("CheckDomain(%q) = %v, want %v", tt.host, err, tt.want)
+		}
+	}
+
+	// Без правил allow разрешено все, кроме запрещенного
+	deny, _ := NewDomainPolicy(nil, []string{".abuse.example"})
+	if err := deny.CheckDomain("any.example.org"); err != nil {
+		t.Errorf("Expected any domain to pass, got %v", err)
+	}
+	if err := deny.CheckDomain("abuse.example"); !errors.Is(err, ErrDomainDenied) {
+		t.Errorf("Expected ErrDomainDenied, got %v", err)
+	}
+}
+
+func TestParseDomainPolicy_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"block example.com",
+		"allow",
+		"allow example.com extra",
+		"deny *",
+		"allow https://example.com",
+	} {
+		if _, err := ParseDomainPolicy(strings.NewReader(input)); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("ParseDomainPolicy(%q): expected ErrInvalidRule, got %v", input, err)
+		}
+	}
+}
+
+func TestDomainPolicyFile_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Chtimes failed: %v", err)
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write("deny evil.example\n", start)
+
+	policy, err := OpenDomainPolicy(path)
+	if err != nil {
+		t.Fatalf("OpenDomainPolicy failed: %v", err)
+	}
+	if reloaded, err := policy.ReloadIfChanged(); reloaded || err != nil {
+		t.Errorf("Expected no reload for unchanged file, got %v, %v", reloaded, err)
+	}
+
+	write("deny evil.example\ndeny worse.example\n", start.Add(time.Minute))
+	if reloaded, err := policy.ReloadIfChanged(); !reloaded || err != nil {
+		t.Fatalf("Expected reload, got %v, %v", reloaded, err)
+	}
+	if err := policy.CheckDomain("worse.example"); !errors.Is(err, ErrDomainDenied) {
+		t.Errorf("Expected new rule to apply, got %v", err)
+	}
+
+	// Ошибка в файле не отменяет действующие правила
+	write("deny\n", start.Add(2*time.Minute))
+	if _, err := policy.ReloadIfChanged(); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("Expected ErrInvalidRule, got %v", err)
+	}
+	if err := policy.CheckDomain("worse.example"); !errors.Is(err, ErrDomainDenied) {
+		t.Errorf("Expected previous rules to stay, got %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package validator
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// DefaultTrackingParams параметры отслеживания, удаляемые по умолчанию
+var DefaultTrackingParams = []string{"utm_*", "fbclid", "gclid"}
+
+// defaultPorts порты, которые не пишутся в каноническом URL
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeURL приводит URL к каноническому виду (RFC 3986, раздел 6):
+// схема и хост в нижнем регистре, IDN в punycode, без порта по умолчанию,
+// без dot-сегментов, с нормализованным percent-encoding, без параметров
+// отслеживания и, если включено, с отсортированным query
+//
+// URL без схемы считается https. Неразбираемый URL возвращается как есть —
+// его отклонит ValidateURL
+func (v *URLValidator) NormalizeURL(rawURL string) string {
+	// URL со своей схемой (в том числе javascript:, data:) не дополняем —
+	// ValidateURL должен увидеть и отклонить ее
+	if !hasScheme(rawURL) {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Opaque != "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = normalizeHost(u.Scheme, u.Host)
+
+	escapedPath := removeDotSegments(normalizeEscapes(u.EscapedPath()))
+	if escapedPath == "" && u.Host != "" {
+		escapedPath = "/"
+	}
+	setEscaped(&u.Path, &u.RawPath, escapedPath)
+
+	u.RawQuery = v.normalizeQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	if u.Fragment != "" {
+		setEscaped(&u.Fragment, &u.RawFragment, normalizeEscapes(u.EscapedFragment()))
+	}
+
+	return u.String()
+}
+
+// normalizeHost переводит хост в нижний регистр и punycode, убирает
+// завершающую точку и порт по умолчанию
+func normalizeHost(scheme, hostport string) string {
+	if hostport == "" {
+		return ""
+	}
+
+	u := url.URL{Host: hostport}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+
+	if strings.Contains(host, ":") {
+		// IPv6-литерал
+		host = "[" + host + "]"
+	} else {
+		if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+			host = ascii
+		}
+		host = strings.TrimSuffix(host, ".")
+	}
+
+	if port == "" || port == defaultPorts[scheme] {
+		return host
+	}
+	return host + ":" + port
+}
+
+// normalizeQuery нормализует percent-encoding параметров, удаляет
+// параметры отслеживания и при необходимости сортирует по имени
+func (v *URLValidator) normalizeQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	type param struct {
+		key  string // для сравнения и сортировки — раскодированное имя
+		pair string
+	}
+
+	var params []param
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		pair = normalizeEscapes(pair)
+		rawKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+
+		if v.isTrackingParam(key) {
+			continue
+		}
+		params = append(params, param{key: key, pair: pair})
+	}
+
+	if v.sortQuery {
+		// Стабильная сортировка сохраняет порядок повторяющихся параметров (a=1&a=2)
+		sort.SliceStable(params, func(i, j int) bool {
+			return params[i].key < params[j].key
+		})
+	}
+
+	pairs := make([]string, len(params))
+	for i, p := range params {
+		pairs[i] = p.pair
+	}
+	return strings.Join(pairs, "&")
+}
+
+// isTrackingParam сообщает, удаляется ли параметр при нормализации
+func (v *URLValidator) isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, pattern := range v.trackingParams {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+			continue
+		}
+		if key == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeEscapes раскодирует незарезервированные символы (%41 → A)
+// и переводит hex-цифры остальных экранирований в верхний регистр (%2f → %2F)
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+
+	return b.String()
+}
+
+// removeDotSegments убирает сегменты . и .. (RFC 3986, раздел 5.2.4)
+// Пустые сегменты (//) сохраняются — для сервера это другой путь
+func removeDotSegments(p string) string {
+	if !strings.Contains(p, ".") {
+		return p
+	}
+
+	segments := strings.Split(p, "/")
+	out := make([]string, 0, len(segments))
+
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		switch segment {
+		case ".":
+		case "..":
+			// out[0] — пустой сегмент перед начальным слэшем, выше корня не поднимаемся
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, segment)
+			continue
+		}
+
+		// /a/b/. и /a/b/.. указывают на директорию — сохраняем завершающий слэш
+		if last {
+			out = append(out, "")
+		}
+	}
+
+	return strings.Join(out, "/")
+}
+
+// setEscaped записывает экранированную и раскодированную формы компонента URL
+func setEscaped(decoded, raw *string, escaped string) {
+	unescaped, err := url.PathUnescape(escaped)
+	if err != nil {
+		return
+	}
+	*decoded = unescaped
+	*raw = escaped
+}
+
+// hasScheme сообщает, начинается ли строка со схемы URL
+// example.com:8080 схемой не считается — после двоеточия идет порт
+func hasScheme(rawURL string) bool {
+	scheme, rest, found := strings.Cut(rawURL, ":")
+	if !found || scheme == "" {
+		return false
+	}
+
+	for i, char := range scheme {
+		isLetter := (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+		if i == 0 && !isLetter {
+			return false
+		}
+		if !isLetter && !(char >= '0' && char <= '9') && char != '+' && char != '-' && char != '.' {
+			return false
+		}
+	}
+
+	port, _, _ := strings.Cut(rest, "/")
+	if port == "" {
+		return true
+	}
+	for _, char := range port {
+		if char < '0' || char > '9' {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnreserved(c byte) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
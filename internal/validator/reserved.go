@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ReservedWords реестр кодов, которые нельзя занимать ссылками:
+// они совпадают с путями сервиса или зарезервированы на будущее
+// Сравнение без учета регистра
+type ReservedWords struct {
+	mu    sync.RWMutex
+	words map[string]struct{}
+}
+
+// NewReservedWords создает реестр из начального списка
+func NewReservedWords(words ...string) *ReservedWords {
+	r := &ReservedWords{words: make(map[string]struct{})}
+	r.Add(words...)
+	return r
+}
+
+// Add добавляет слова в реестр; пустые строки пропускаются
+func (r *ReservedWords) Add(words ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" {
+			r.words[word] = struct{}{}
+		}
+	}
+}
+
+// Contains сообщает, зарезервирован ли код
+func (r *ReservedWords) Contains(code string) bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.words[strings.ToLower(code)]
+	return exists
+}
+
+// Blocked реализует generator.Filter — генератор не выдаст зарезервированный код
+func (r *ReservedWords) Blocked(code string) bool {
+	return r.Contains(code)
+}
+
+// List возвращает отсортированный список слов
+func (r *ReservedWords) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	words := make([]string, 0, len(r.words))
+	for word := range r.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	return words
+}
@@ -1,33 +1,144 @@
 package validator
 
 import (
+	"context"
 	"errors"
+	"net/netip"
 	"net/url"
 	"strings"
+	"time"
+
+	"github.com/dmitrycr/ShortUrl/pkg/blocklist"
 )
 
 var (
-	ErrInvalidURL    = errors.New("invalid URL format")
-	ErrURLTooLong    = errors.New("URL exceeds maximum length")
-	ErrInvalidScheme = errors.New("URL must use http or https scheme")
-	ErrInvalidCode   = errors.New("invalid short code format")
-	ErrCodeTooLong   = errors.New("short code exceeds maximum length")
+	ErrInvalidURL       = errors.New("invalid URL format")
+	ErrURLTooLong       = errors.New("URL exceeds maximum length")
+	ErrInvalidScheme    = errors.New("URL must use http or https scheme")
+	ErrMissingHost      = errors.New("URL must contain a host")
+	ErrCredentials      = errors.New("URL must not contain credentials")
+	ErrPrivateAddress   = errors.New("URL points to a private or reserved address")
+	ErrUnresolvableHost = errors.New("URL host cannot be resolved")
+	ErrInvalidCode      = errors.New("invalid short code format")
+	ErrCodeTooLong      = errors.New("short code exceeds maximum length")
+	ErrBlockedCode      = errors.New("short code contains a blocked word")
+	ErrReservedCode     = errors.New("short code is reserved")
 )
 
 const (
 	MaxURLLength  = 2048
 	MaxCodeLength = 20
 	MinCodeLength = 3
+
+	// resolveTimeout ограничение на DNS-запрос при проверке хоста
+	resolveTimeout = 3 * time.Second
 )
 
-type URLValidator struct{}
+// DefaultSchemes схемы, разрешенные по умолчанию
+var DefaultSchemes = []string{"http", "https"}
+
+// Resolver разрешает имя хоста в адреса; *net.Resolver подходит
+// В тестах подменяется, чтобы проверка работала без сети
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+type URLValidator struct {
+	blocklist *blocklist.Blocklist
+	reserved  *ReservedWords
+	foldCase  bool
 
-func NewURLValidator() *URLValidator {
-	return &URLValidator{}
+	schemes  map[string]struct{}
+	resolver Resolver // nil — имена хостов не разрешаются, проверяются только IP-литералы
+	domains  DomainChecker
+
+	// Канонизация URL: удаляемые параметры отслеживания и сортировка query
+	trackingParams []string
+	sortQuery      bool
+}
+
+// Option настраивает URLValidator
+type Option func(*URLValidator)
+
+// WithBlocklist запрещает кастомные коды, содержащие слова из списка
+func WithBlocklist(b *blocklist.Blocklist) Option {
+	return func(v *URLValidator) {
+		v.blocklist = b
+	}
+}
+
+// WithReservedWords запрещает кастомные коды из реестра зарезервированных слов
+func WithReservedWords(r *ReservedWords) Option {
+	return func(v *URLValidator) {
+		v.reserved = r
+	}
+}
+
+// WithCaseInsensitiveCodes приводит коды к нижнему регистру (см. NormalizeCode)
+func WithCaseInsensitiveCodes() Option {
+	return func(v *URLValidator) {
+		v.foldCase = true
+	}
+}
+
+// WithAllowedSchemes задает разрешенные схемы URL (по умолчанию DefaultSchemes)
+func WithAllowedSchemes(schemes ...string) Option {
+	return func(v *URLValidator) {
+		v.schemes = make(map[string]struct{}, len(schemes))
+		for _, scheme := range schemes {
+			v.schemes[strings.ToLower(scheme)] = struct{}{}
+		}
+	}
+}
+
+// WithResolver включает проверку адресов, в которые разрешается имя хоста
+func WithResolver(r Resolver) Option {
+	return func(v *URLValidator) {
+		v.resolver = r
+	}
+}
+
+// WithDomainPolicy ограничивает домены целевых URL (см. ValidateDomain)
+func WithDomainPolicy(c DomainChecker) Option {
+	return func(v *URLValidator) {
+		v.domains = c
+	}
+}
+
+// WithTrackingParams задает параметры query, удаляемые при нормализации
+// (по умолчанию DefaultTrackingParams). Шаблон с * на конце — префикс: utm_*
+func WithTrackingParams(params ...string) Option {
+	return func(v *URLValidator) {
+		v.trackingParams = make([]string, 0, len(params))
+		for _, param := range params {
+			v.trackingParams = append(v.trackingParams, strings.ToLower(param))
+		}
+	}
+}
+
+// WithQuerySorting сортирует параметры query по имени при нормализации
+// Порядок параметров обычно не важен серверу, но делает одинаковые ссылки разными
+func WithQuerySorting(enabled bool) Option {
+	return func(v *URLValidator) {
+		v.sortQuery = enabled
+	}
+}
+
+func NewURLValidator(opts ...Option) *URLValidator {
+	v := &URLValidator{}
+	WithAllowedSchemes(DefaultSchemes...)(v)
+	WithTrackingParams(DefaultTrackingParams...)(v)
+
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
 }
 
-// ValidateURL проверяет корректность URL
-func (v *URLValidator) ValidateURL(rawURL string) error {
+// ValidateURL проверяет, что URL корректен и безопасен как цель редиректа:
+// разрешенная схема, есть хост, нет учетных данных, хост не ведет
+// во внутреннюю сеть (ни IP-литералом, ни через DNS при заданном Resolver)
+func (v *URLValidator) ValidateURL(ctx context.Context, rawURL string) error {
 	// Проверка длины
 	if len(rawURL) > MaxURLLength {
 		return ErrURLTooLong
@@ -37,13 +148,77 @@ func (v *URLValidator) ValidateURL(rawURL string) error {
 		return ErrInvalidURL
 	}
 
-	_, err := url.ParseRequestURI(rawURL)
-	if err != nil {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" {
 		return ErrInvalidURL
 	}
+
+	if _, ok := v.schemes[strings.ToLower(u.Scheme)]; !ok {
+		return ErrInvalidScheme
+	}
+
+	if u.User != nil {
+		return ErrCredentials
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return ErrMissingHost
+	}
+
+	return v.validateHost(ctx, host)
+}
+
+// validateHost отклоняет хосты, ведущие во внутренние и зарезервированные сети
+func (v *URLValidator) validateHost(ctx context.Context, host string) error {
+	if addr, ok := parseHostAddr(host); ok {
+		if isBlockedAddr(addr) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+
+	if isLocalHostname(host) {
+		return ErrPrivateAddress
+	}
+
+	if v.resolver == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	addrs, err := v.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return ErrUnresolvableHost
+	}
+
+	// Достаточно одного внутреннего адреса: клиент может выбрать любой
+	for _, addr := range addrs {
+		if isBlockedAddr(addr) {
+			return ErrPrivateAddress
+		}
+	}
+
 	return nil
 }
 
+// ValidateDomain проверяет хост URL по политике доменов
+// Ожидает URL, уже прошедший ValidateURL
+func (v *URLValidator) ValidateDomain(rawURL string) error {
+	if v.domains == nil {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ErrInvalidURL
+	}
+
+	return v.domains.CheckDomain(u.Hostname())
+}
+
 func (v *URLValidator) ValidateCustomCode(code string) error {
 	if len(code) == 0 {
 		return ErrCodeTooLong
@@ -63,14 +238,28 @@ func (v *URLValidator) ValidateCustomCode(code string) error {
 		}
 	}
 
+	code = v.NormalizeCode(code)
+
+	if v.reserved.Contains(code) {
+		return ErrReservedCode
+	}
+
+	// Кастомный код проверяется по границам слов: запрещенное слово внутри
+	// обычного («scunthorpe») его не отвергает
+	if _, found := v.blocklist.MatchWord(code); found {
+		return ErrBlockedCode
+	}
+
 	return nil
 }
 
-func (v *URLValidator) NormalizeURL(rawURL string) string {
-	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
-		return "https://" + rawURL
+// NormalizeCode приводит код к каноническому виду: в режиме без учета
+// регистра — к нижнему регистру, иначе код не меняется
+func (v *URLValidator) NormalizeCode(code string) string {
+	if v.foldCase {
+		return strings.ToLower(code)
 	}
-	return rawURL
+	return code
 }
 
 // isValidCodeChar проверяет, допустим ли символ в коде
@@ -0,0 +1,17 @@
+// Package migrations встраивает SQL-схему базы данных в бинарник
+//
+// Файлы именуются NNN_name.up.sql / NNN_name.down.sql и применяются
+// по возрастанию номера (см. internal/migrate)
+package migrations
+
+import "embed"
+
+// Postgres содержит миграции для PostgreSQL (директория postgres)
+//
+//go:embed postgres/*.sql
+var Postgres embed.FS
+
+// SQLite содержит миграции для SQLite (директория sqlite)
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
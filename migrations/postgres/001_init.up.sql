@@ -8,13 +8,13 @@ CREATE TABLE IF NOT EXISTS urls (
 );
 
 -- Индекс для быстрого поиска по short_code
-CREATE INDEX idx_urls_short_code ON urls(short_code);
+CREATE INDEX IF NOT EXISTS idx_urls_short_code ON urls(short_code);
 
 -- Индекс для поиска истекших ссылок
-CREATE INDEX idx_urls_expires_at ON urls(expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_urls_expires_at ON urls(expires_at) WHERE expires_at IS NOT NULL;
 
 -- Индекс для сортировки по дате создания
-CREATE INDEX idx_urls_created_at ON urls(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_urls_created_at ON urls(created_at DESC);
 
 -- Комментарии к таблице и колонкам
 COMMENT ON TABLE urls IS 'Таблица для хранения сокращенных URL';
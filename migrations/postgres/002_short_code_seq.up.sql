@@ -0,0 +1,2 @@
+-- Последовательность для генерации кодов из ID (CODE_STRATEGY=sequence)
+CREATE SEQUENCE IF NOT EXISTS short_code_seq;
@@ -0,0 +1,7 @@
+-- Коды, зарезервированные пулами экземпляров сервиса, но еще не выданные
+CREATE TABLE IF NOT EXISTS reserved_codes (
+    code VARCHAR(20) PRIMARY KEY,
+    reserved_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE reserved_codes IS 'Пул заранее сгенерированных коротких кодов';
@@ -0,0 +1,9 @@
+-- Результат последней проверки целевого URL фоновым чекером
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_status INTEGER;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP;
+
+-- Выбор ссылок для очередной проверки: сначала непроверенные, затем самые давние
+CREATE INDEX IF NOT EXISTS idx_urls_last_checked_at ON urls(last_checked_at);
+
+COMMENT ON COLUMN urls.last_status IS 'HTTP-статус последней проверки (0 = запрос не удался, NULL = не проверялась)';
+COMMENT ON COLUMN urls.last_checked_at IS 'Дата и время последней проверки целевого URL';
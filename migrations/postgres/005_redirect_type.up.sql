@@ -0,0 +1,5 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_type SMALLINT NOT NULL DEFAULT 302;
+
+ALTER TABLE urls ADD CONSTRAINT urls_redirect_type_check CHECK (redirect_type IN (301, 302, 307, 308));
+
+COMMENT ON COLUMN urls.redirect_type IS 'HTTP-статус редиректа: 301, 302, 307 или 308';
@@ -0,0 +1,3 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS interstitial BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN urls.interstitial IS 'Показывать страницу подтверждения вместо редиректа';
@@ -0,0 +1,3 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN urls.password_hash IS 'Хеш пароля ссылки (pbkdf2-sha256), пусто — без пароля';
@@ -0,0 +1,2 @@
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_max_clicks_check;
+ALTER TABLE urls DROP COLUMN IF EXISTS max_clicks;
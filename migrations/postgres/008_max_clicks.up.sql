@@ -0,0 +1,5 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS max_clicks BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE urls ADD CONSTRAINT urls_max_clicks_check CHECK (max_clicks >= 0);
+
+COMMENT ON COLUMN urls.max_clicks IS 'Сколько переходов разрешено, 0 — без ограничения';
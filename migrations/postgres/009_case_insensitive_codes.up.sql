@@ -0,0 +1,25 @@
+-- migrate:feature case_insensitive_codes
+-- Уникальность и поиск кодов без учета регистра (CODE_CASE_INSENSITIVE=true)
+-- Коды, совпадающие без учета регистра, прерывают миграцию со списком конфликтов
+DO $$
+DECLARE
+    total BIGINT;
+    examples TEXT;
+BEGIN
+    SELECT count(*), string_agg(codes, ', ' ORDER BY key) FILTER (WHERE n <= 5)
+    INTO total, examples
+    FROM (
+        SELECT lower(short_code) AS key,
+               string_agg(short_code, '/' ORDER BY short_code) AS codes,
+               row_number() OVER (ORDER BY lower(short_code)) AS n
+        FROM urls
+        GROUP BY lower(short_code)
+        HAVING count(*) > 1
+    ) AS conflicts;
+
+    IF total > 0 THEN
+        RAISE EXCEPTION 'case conflicts: % groups (%); rename them before enabling case-insensitive codes', total, examples;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_short_code_lower ON urls (lower(short_code));
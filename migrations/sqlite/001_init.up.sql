@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS urls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    original_url TEXT NOT NULL,
+    short_code VARCHAR(20) NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME,
+    click_count INTEGER NOT NULL DEFAULT 0
+);
+
+-- Индекс для поиска истекших ссылок
+CREATE INDEX IF NOT EXISTS idx_urls_expires_at ON urls(expires_at) WHERE expires_at IS NOT NULL;
+
+-- Индекс для сортировки по дате создания
+CREATE INDEX IF NOT EXISTS idx_urls_created_at ON urls(created_at DESC);
@@ -0,0 +1,5 @@
+-- Именованные счетчики; short_code — для генерации кодов из ID (CODE_STRATEGY=sequence)
+CREATE TABLE IF NOT EXISTS sequences (
+    name TEXT PRIMARY KEY,
+    value INTEGER NOT NULL
+);
@@ -0,0 +1,5 @@
+-- Коды, зарезервированные пулом, но еще не выданные
+CREATE TABLE IF NOT EXISTS reserved_codes (
+    code VARCHAR(20) PRIMARY KEY,
+    reserved_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
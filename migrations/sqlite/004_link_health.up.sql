@@ -0,0 +1,5 @@
+-- Результат последней проверки целевого URL (0 = запрос не удался, NULL = не проверялась)
+ALTER TABLE urls ADD COLUMN last_status INTEGER;
+ALTER TABLE urls ADD COLUMN last_checked_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_urls_last_checked_at ON urls(last_checked_at);
@@ -0,0 +1,2 @@
+-- HTTP-статус редиректа: 301, 302, 307 или 308
+ALTER TABLE urls ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 302;
@@ -0,0 +1,2 @@
+-- Показывать страницу подтверждения вместо редиректа
+ALTER TABLE urls ADD COLUMN interstitial INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Хеш пароля ссылки (pbkdf2-sha256), пусто — без пароля
+ALTER TABLE urls ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Сколько переходов разрешено, 0 — без ограничения
+ALTER TABLE urls ADD COLUMN max_clicks INTEGER NOT NULL DEFAULT 0;
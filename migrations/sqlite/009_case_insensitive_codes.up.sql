@@ -0,0 +1,29 @@
+-- migrate:feature case_insensitive_codes
+-- Уникальность и поиск кодов без учета регистра (CODE_CASE_INSENSITIVE=true)
+-- Коды, совпадающие без учета регистра, прерывают миграцию со списком конфликтов:
+-- в SQLite нет процедур, поэтому ошибку поднимает временный триггер
+CREATE TEMP TABLE case_conflicts (total INTEGER NOT NULL, examples TEXT);
+
+CREATE TEMP TRIGGER case_conflicts_abort BEFORE INSERT ON case_conflicts
+WHEN NEW.total > 0
+BEGIN
+    SELECT RAISE(ABORT, 'case conflicts: ' || NEW.total || ' groups (' || NEW.examples || '); rename them before enabling case-insensitive codes');
+END;
+
+INSERT INTO case_conflicts (total, examples)
+SELECT
+    (SELECT count(*) FROM (
+        SELECT 1 FROM urls GROUP BY lower(short_code) HAVING count(*) > 1
+    )),
+    (SELECT group_concat(codes, ', ') FROM (
+        SELECT group_concat(short_code, '/' ORDER BY short_code) AS codes
+        FROM urls
+        GROUP BY lower(short_code)
+        HAVING count(*) > 1
+        ORDER BY lower(short_code)
+        LIMIT 5
+    ));
+
+DROP TABLE case_conflicts;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_short_code_lower ON urls (lower(short_code));
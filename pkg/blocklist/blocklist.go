@@ -0,0 +1,159 @@
+// Package blocklist проверяет короткие коды на нежелательные слова
+//
+// Сравнение идет по канонической форме: регистр, разделители и leetspeak
+// (0→o, 1→i, 3→e, 4→a, 5→s, 7→t, @→a, $→s ...) не влияют на результат
+//
+// Match ищет слово где угодно в коде — для сгенерированных кодов, которые
+// можно просто перегенерировать. MatchWord требует совпадения по границам
+// сегментов — для кастомных: иначе отвергались бы невинные слова с запрещенным
+// внутри (scunthorpe)
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Blocklist набор запрещенных слов
+type Blocklist struct {
+	words []string            // в канонической форме
+	set   map[string]struct{} // те же слова для MatchWord
+}
+
+// New создает список из слов; пустые строки пропускаются
+func New(words []string) *Blocklist {
+	b := &Blocklist{set: make(map[string]struct{}, len(words))}
+
+	for _, word := range words {
+		canonical := canonicalize(word)
+		if canonical == "" {
+			continue
+		}
+		if _, dup := b.set[canonical]; dup {
+			continue
+		}
+		b.set[canonical] = struct{}{}
+		b.words = append(b.words, canonical)
+	}
+
+	return b
+}
+
+// Load читает список из файла: одно слово на строку, # — комментарий
+func Load(path string) (*Blocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blocklist: %w", err)
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+
+	return New(words), nil
+}
+
+// Len возвращает количество слов в списке
+func (b *Blocklist) Len() int {
+	return len(b.words)
+}
+
+// Match возвращает первое запрещенное слово, встречающееся в коде
+func (b *Blocklist) Match(code string) (string, bool) {
+	if b == nil || len(b.words) == 0 {
+		return "", false
+	}
+
+	canonical := canonicalize(code)
+	for _, word := range b.words {
+		if strings.Contains(canonical, word) {
+			return word, true
+		}
+	}
+
+	return "", false
+}
+
+// MatchWord возвращает запрещенное слово, совпадающее с сегментом кода
+// (части между - _ . ) или несколькими соседними сегментами целиком:
+// «bad-word» и «b4dw0rd» найдутся, а «scunthorpe» — нет
+func (b *Blocklist) MatchWord(code string) (string, bool) {
+	if b == nil || len(b.words) == 0 {
+		return "", false
+	}
+
+	segments := strings.FieldsFunc(code, isSeparator)
+	for i := range segments {
+		var joined strings.Builder
+		for _, segment := range segments[i:] {
+			joined.WriteString(canonicalize(segment))
+			if _, found := b.set[joined.String()]; found {
+				return joined.String(), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Blocked сообщает, содержит ли код запрещенное слово
+// Реализует generator.Filter
+func (b *Blocklist) Blocked(code string) bool {
+	_, found := b.Match(code)
+	return found
+}
+
+// canonicalize приводит строку к нижнему регистру, убирает разделители
+// и сводит похожие по начертанию символы к одной букве
+func canonicalize(s string) string {
+	var result strings.Builder
+	result.Grow(len(s))
+
+	for _, r := range strings.ToLower(s) {
+		if isSeparator(r) {
+			continue
+		}
+		switch r {
+		case '0':
+			r = 'o'
+		case '1', 'l', '!', '|':
+			r = 'i'
+		case '2':
+			r = 'z'
+		case '3':
+			r = 'e'
+		case '4', '@':
+			r = 'a'
+		case '5', '$':
+			r = 's'
+		case '6':
+			r = 'g'
+		case '7', '+':
+			r = 't'
+		case '8':
+			r = 'b'
+		case '9':
+			r = 'g'
+		}
+		result.WriteRune(r)
+	}
+
+	return result.String()
+}
+
+func isSeparator(r rune) bool {
+	return r == '-' || r == '_' || r == '.' || r == ' '
+}
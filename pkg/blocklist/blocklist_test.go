@@ -0,0 +1,62 @@
+package blocklist
+
+import "testing"
+
+func TestBlocklist_Match(t *testing.T) {
+	b := New([]string{"badword", "evil", ""})
+
+	tests := []struct {
+		code    string
+		blocked bool
+	}{
+		{"xBadWordx", true},
+		{"b4dw0rd", true},
+		{"bad-word", true},
+		{"3v1l", true},
+		{"EVIL_ab", true},
+		{"good42", false},
+		{"eviI", true}, // заглавная I похожа на l
+		{"ev", false},
+	}
+
+	for _, tt := range tests {
+		if got := b.Blocked(tt.code); got != tt.blocked {
+			t.Errorf("Blocked(%q) = %v, want %v", tt.code, got, tt.blocked)
+		}
+	}
+
+	if b.Len() != 2 {
+		t.Errorf("Expected 2 words, got %d", b.Len())
+	}
+}
+
+func TestBlocklist_MatchWord(t *testing.T) {
+	b := New([]string{"badword", "evil", "cunt"})
+
+	tests := []struct {
+		code    string
+		blocked bool
+	}{
+		{"badword", true},
+		{"b4dw0rd", true},
+		{"bad-word", true},
+		{"promo-3v1l-2024", true},
+		{"EVIL_ab", true},
+		// Запрещенное слово внутри обычного — не повод отвергать код
+		{"scunthorpe", false},
+		{"xBadWordx", false},
+		{"medieval", false},
+		{"good42", false},
+	}
+
+	for _, tt := range tests {
+		if _, found := b.MatchWord(tt.code); found != tt.blocked {
+			t.Errorf("MatchWord(%q) = %v, want %v", tt.code, found, tt.blocked)
+		}
+	}
+
+	// Сгенерированные коды по-прежнему проверяются по подстроке
+	if !b.Blocked("scunthorpe") {
+		t.Error("Expected substring match for generated codes")
+	}
+}
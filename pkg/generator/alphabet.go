@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	lowercaseChars   = "0123456789abcdefghijklmnopqrstuvwxyz"
+	crockfordChars   = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	unambiguousChars = "23456789abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+// Alphabet набор символов, из которых строятся коды
+type Alphabet struct {
+	name  string
+	chars string
+
+	// normalize приводит введенный пользователем код к каноническому виду
+	// (например, Crockford не различает регистр и путаемые символы)
+	normalize func(string) string
+}
+
+var (
+	// Base62 цифры и латиница в обоих регистрах (по умолчанию)
+	Base62 = Alphabet{name: "base62", chars: base62Chars}
+
+	// Lowercase цифры и строчная латиница — коды не зависят от регистра при вводе
+	Lowercase = Alphabet{name: "lowercase", chars: lowercaseChars, normalize: strings.ToLower}
+
+	// Crockford base32 Дугласа Крокфорда: без I, L, O, U; при декодировании
+	// регистр игнорируется, O читается как 0, I и L — как 1
+	Crockford = Alphabet{name: "crockford", chars: crockfordChars, normalize: normalizeCrockford}
+
+	// Unambiguous base62 без символов, которые путают при чтении вслух и печати: 0 O o 1 l I
+	Unambiguous = Alphabet{name: "unambiguous", chars: unambiguousChars}
+)
+
+// CustomAlphabet создает алфавит из произвольного набора ASCII-символов
+func CustomAlphabet(chars string) (Alphabet, error) {
+	if len(chars) < 2 {
+		return Alphabet{}, fmt.Errorf("%w: need at least 2 characters", ErrInvalidAlphabet)
+	}
+
+	if len(chars) != utf8.RuneCountInString(chars) {
+		return Alphabet{}, fmt.Errorf("%w: only ASCII characters are supported", ErrInvalidAlphabet)
+	}
+
+	for i := 0; i < len(chars); i++ {
+		if strings.IndexByte(chars[i+1:], chars[i]) != -1 {
+			return Alphabet{}, fmt.Errorf("%w: duplicate character %q", ErrInvalidAlphabet, chars[i])
+		}
+	}
+
+	return Alphabet{name: "custom", chars: chars}, nil
+}
+
+// ParseAlphabet возвращает алфавит по имени: base62, lowercase, crockford,
+// unambiguous или custom:<символы>
+func ParseAlphabet(name string) (Alphabet, error) {
+	if chars, ok := strings.CutPrefix(name, "custom:"); ok {
+		return CustomAlphabet(chars)
+	}
+
+	switch name {
+	case "", Base62.name:
+		return Base62, nil
+	case Lowercase.name:
+		return Lowercase, nil
+	case Crockford.name:
+		return Crockford, nil
+	case Unambiguous.name:
+		return Unambiguous, nil
+	default:
+		return Alphabet{}, fmt.Errorf("%w: unknown alphabet %q", ErrInvalidAlphabet, name)
+	}
+}
+
+// CaseInsensitive возвращает вариант алфавита, в котором регистр не важен:
+// коды генерируются в нижнем регистре, ввод приводится к нему же
+// Алфавиты с обоими регистрами теряют заглавные буквы (base62 становится lowercase)
+func (a Alphabet) CaseInsensitive() (Alphabet, error) {
+	folded := Alphabet{name: a.name, normalize: strings.ToLower}
+
+	if a.normalize != nil {
+		// Нормализация уже склеивает регистры (crockford, lowercase) — достаточно
+		// перевести символы и нормализацию в нижний регистр
+		folded.chars = strings.ToLower(a.chars)
+		folded.normalize = func(code string) string {
+			return strings.ToLower(a.normalize(code))
+		}
+		return folded, nil
+	}
+
+	folded.chars = strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return -1
+		}
+		return r
+	}, a.chars)
+
+	if len(folded.chars) < 2 {
+		return Alphabet{}, fmt.Errorf("%w: %s has no lowercase characters", ErrInvalidAlphabet, a.name)
+	}
+
+	return folded, nil
+}
+
+// String возвращает имя алфавита
+func (a Alphabet) String() string {
+	return a.name
+}
+
+// Chars возвращает символы алфавита
+func (a Alphabet) Chars() string {
+	return a.chars
+}
+
+// Normalize приводит код к каноническому виду алфавита
+func (a Alphabet) Normalize(code string) string {
+	if a.normalize == nil {
+		return code
+	}
+	return a.normalize(code)
+}
+
+// index возвращает позицию символа в алфавите или -1
+func (a Alphabet) index(char byte) int {
+	return strings.IndexByte(a.chars, char)
+}
+
+// normalizeCrockford переводит в верхний регистр и заменяет путаемые символы
+func normalizeCrockford(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case 'o', 'O':
+			return '0'
+		case 'i', 'I', 'l', 'L':
+			return '1'
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, code)
+}
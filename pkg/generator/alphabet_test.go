@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAlphabets(t *testing.T) {
+	for _, alphabet := range []Alphabet{Base62, Lowercase, Crockford, Unambiguous} {
+		t.Run(alphabet.String(), func(t *testing.T) {
+			g := NewGenerator(8, WithAlphabet(alphabet))
+
+			code, err := g.Generate()
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			for _, char := range code {
+				if !strings.ContainsRune(alphabet.Chars(), char) {
+					t.Fatalf("Code %q contains %q outside of alphabet", code, char)
+				}
+			}
+			if !g.ValidateCode(code) {
+				t.Errorf("ValidateCode(%q) = false", code)
+			}
+
+			id, err := g.DecodeID(g.EncodeID(123456789))
+			if err != nil || id != 123456789 {
+				t.Errorf("EncodeID/DecodeID round trip = %d, %v", id, err)
+			}
+		})
+	}
+}
+
+func TestCrockfordNormalization(t *testing.T) {
+	g := NewGenerator(6, WithAlphabet(Crockford))
+
+	want, _ := g.DecodeID("10ABZ")
+	got, err := g.DecodeID("lOabz")
+	if err != nil || got != want {
+		t.Errorf("DecodeID(lOabz) = %d, %v; want %d", got, err, want)
+	}
+
+	if g.ValidateCode("ABCU") {
+		t.Error("Expected U to be rejected by Crockford alphabet")
+	}
+}
+
+func TestParseAlphabet(t *testing.T) {
+	alphabet, err := ParseAlphabet("custom:abc")
+	if err != nil || alphabet.Chars() != "abc" {
+		t.Errorf("ParseAlphabet(custom:abc) = %q, %v", alphabet.Chars(), err)
+	}
+
+	for _, name := range []string{"klingon", "custom:a", "custom:aab", "custom:äb"} {
+		if _, err := ParseAlphabet(name); !errors.Is(err, ErrInvalidAlphabet) {
+			t.Errorf("ParseAlphabet(%q): expected ErrInvalidAlphabet, got %v", name, err)
+		}
+	}
+}
+
+func TestCaseInsensitiveAlphabet(t *testing.T) {
+	tests := []struct {
+		alphabet Alphabet
+		chars    string
+	}{
+		{Base62, lowercaseChars},
+		{Lowercase, lowercaseChars},
+		{Crockford, strings.ToLower(crockfordChars)},
+		{Unambiguous, "23456789abcdefghijkmnpqrstuvwxyz"},
+	}
+
+	for _, tt := range tests {
+		folded, err := tt.alphabet.CaseInsensitive()
+		if err != nil {
+			t.Fatalf("%s: CaseInsensitive failed: %v", tt.alphabet, err)
+		}
+		if folded.Chars() != tt.chars {
+			t.Errorf("%s: expected chars %q, got %q", tt.alphabet, tt.chars, folded.Chars())
+		}
+	}
+
+	folded, _ := Crockford.CaseInsensitive()
+	if got := folded.Normalize("AbOL"); got != "ab01" {
+		t.Errorf("Expected Normalize(AbOL) = ab01, got %q", got)
+	}
+
+	upper, _ := CustomAlphabet("ABC")
+	if _, err := upper.CaseInsensitive(); !errors.Is(err, ErrInvalidAlphabet) {
+		t.Errorf("Expected ErrInvalidAlphabet, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package generator
+
+// Контрольный символ — Luhn mod N над алфавитом генератора
+//
+// Ловит любую замену одного символа и большинство перестановок соседних;
+// по неверному коду можно предложить исправления (Corrections)
+
+// WithChecksum добавляет в конец кода контрольный символ
+// Длина кода не меняется: случайных символов становится на один меньше
+func WithChecksum() Option {
+	return func(g *Generator) {
+		g.checksum = true
+	}
+}
+
+// Checksum сообщает, добавляет ли генератор контрольный символ
+func (g *Generator) Checksum() bool {
+	return g.checksum
+}
+
+// VerifyChecksum проверяет контрольный символ кода
+// Код нормализуется алфавитом; символы вне алфавита — ошибка проверки
+func (g *Generator) VerifyChecksum(code string) bool {
+	code = g.alphabet.Normalize(code)
+	if len(code) < 2 {
+		return false
+	}
+
+	sum, ok := g.luhnSum(code, false)
+	return ok && sum%len(g.alphabet.chars) == 0
+}
+
+// Corrections возвращает коды с верным контрольным символом, отличающиеся
+// от code заменой одного символа или перестановкой двух соседних
+func (g *Generator) Corrections(code string) []string {
+	code = g.alphabet.Normalize(code)
+	chars := g.alphabet.chars
+
+	seen := map[string]struct{}{code: {}}
+	var corrections []string
+
+	add := func(candidate string) {
+		if _, dup := seen[candidate]; dup {
+			return
+		}
+		seen[candidate] = struct{}{}
+		if g.VerifyChecksum(candidate) {
+			corrections = append(corrections, candidate)
+		}
+	}
+
+	buf := []byte(code)
+	for i := range buf {
+		original := buf[i]
+		for j := 0; j < len(chars); j++ {
+			buf[i] = chars[j]
+			add(string(buf))
+		}
+		buf[i] = original
+	}
+
+	for i := 0; i+1 < len(buf); i++ {
+		buf[i], buf[i+1] = buf[i+1], buf[i]
+		add(string(buf))
+		buf[i], buf[i+1] = buf[i+1], buf[i]
+	}
+
+	return corrections
+}
+
+// checkChar вычисляет контрольный символ для payload
+func (g *Generator) checkChar(payload string) byte {
+	n := len(g.alphabet.chars)
+	sum, _ := g.luhnSum(payload, true)
+	return g.alphabet.chars[(n-sum%n)%n]
+}
+
+// luhnSum сумма Luhn mod N; справа налево каждый второй символ удваивается
+// Для payload без контрольного символа удвоение начинается с последнего символа
+func (g *Generator) luhnSum(s string, payload bool) (int, bool) {
+	n := len(g.alphabet.chars)
+
+	factor := 1
+	if payload {
+		factor = 2
+	}
+
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		index := g.alphabet.index(s[i])
+		if index < 0 {
+			return 0, false
+		}
+
+		addend := factor * index
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+
+	return sum, true
+}
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	for _, alphabet := range []Alphabet{Base62, Lowercase, Crockford, Unambiguous} {
+		t.Run(alphabet.String(), func(t *testing.T) {
+			g := NewGenerator(7, WithAlphabet(alphabet), WithChecksum())
+
+			code, err := g.Generate()
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if len(code) != 7 {
+				t.Fatalf("Expected code length 7, got %q", code)
+			}
+			if !g.VerifyChecksum(code) {
+				t.Fatalf("VerifyChecksum(%q) = false", code)
+			}
+
+			// Любая замена одного символа обнаруживается
+			chars := alphabet.Chars()
+			for i := range code {
+				for j := 0; j < len(chars); j++ {
+					if chars[j] == code[i] {
+						continue
+					}
+					typo := code[:i] + string(chars[j]) + code[i+1:]
+					if g.VerifyChecksum(typo) {
+						t.Fatalf("Substitution %q of %q passed checksum", typo, code)
+					}
+
+					if !slices.Contains(g.Corrections(typo), code) {
+						t.Fatalf("Corrections(%q) does not contain %q", typo, code)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSequenceGenerator_Checksum(t *testing.T) {
+	g, err := NewSequenceGenerator(4, []byte("secret"), WithChecksum())
+	if err != nil {
+		t.Fatalf("NewSequenceGenerator failed: %v", err)
+	}
+
+	for id := int64(0); id < 100; id++ {
+		code, err := g.Code(id)
+		if err != nil {
+			t.Fatalf("Code(%d) failed: %v", id, err)
+		}
+		if len(code) != 4 || !g.gen.VerifyChecksum(code) {
+			t.Fatalf("Code(%d) = %q has no valid check character", id, code)
+		}
+
+		back, err := g.ID(code)
+		if err != nil || back != id {
+			t.Fatalf("ID(%q) = %d, %v; want %d", code, back, err, id)
+		}
+	}
+}
@@ -3,5 +3,11 @@ package generator
 import "errors"
 
 var (
-	ErrInvalidLength = errors.New("invalid length for code generation")
+	ErrInvalidLength    = errors.New("invalid length for code generation")
+	ErrInvalidCode      = errors.New("invalid code")
+	ErrEmptyKey         = errors.New("sequence key must not be empty")
+	ErrIDOutOfRange     = errors.New("id is out of range for code length")
+	ErrInvalidAlphabet  = errors.New("invalid alphabet")
+	ErrBlockedCode      = errors.New("code rejected by filter")
+	ErrInvalidSeparator = errors.New("word separator must be - or _")
 )
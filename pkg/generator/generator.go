@@ -3,6 +3,7 @@ package generator
 import (
 	"crypto/rand"
 	"fmt"
+	"math"
 	"math/big"
 	"strings"
 )
@@ -10,61 +11,146 @@ import (
 const (
 	base62Chars   = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	defaultLength = 6 // Длина кода по умолчанию
-	//maxRetries    = 3
+
+	// maxFilterAttempts сколько раз Generate перегенерирует код, отвергнутый фильтром
+	maxFilterAttempts = 100
 )
 
 type Generator struct {
-	length int
+	length   int
+	alphabet Alphabet
+	filters  []Filter
+	checksum bool // последний символ — контрольный (WithChecksum)
+}
+
+// Filter отбраковывает нежелательные коды (например, blocklist.Blocklist)
+type Filter interface {
+	Blocked(code string) bool
+}
+
+// Option настраивает Generator
+type Option func(*Generator)
+
+// WithAlphabet задает алфавит кодов (по умолчанию Base62)
+func WithAlphabet(alphabet Alphabet) Option {
+	return func(g *Generator) {
+		g.alphabet = alphabet
+	}
 }
 
+// CodeGenerator источник случайных кодов: Generator (символы алфавита)
+// или WordGenerator (слова)
 type CodeGenerator interface {
-	GenerateUnique() (string, error)
+	Generate() (string, error)
+
+	// Capacity количество различных кодов
+	Capacity() float64
 }
 
-func NewGenerator(length int) *Generator {
+func NewGenerator(length int, opts ...Option) *Generator {
 	if length <= 0 {
 		length = defaultLength
 	}
 
-	return &Generator{length: length}
+	g := &Generator{
+		length:   length,
+		alphabet: Base62,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// WithFilter добавляет фильтр: Generate перегенерирует код, пока его отвергает хоть один фильтр
+func WithFilter(filter Filter) Option {
+	return func(g *Generator) {
+		g.filters = append(g.filters, filter)
+	}
+}
+
+// Alphabet возвращает алфавит генератора
+func (g *Generator) Alphabet() Alphabet {
+	return g.alphabet
+}
+
+// Length возвращает длину генерируемых кодов (вместе с контрольным символом)
+func (g *Generator) Length() int {
+	return g.length
+}
+
+// Capacity возвращает количество различных кодов
+func (g *Generator) Capacity() float64 {
+	return math.Pow(float64(len(g.alphabet.chars)), float64(g.payloadLength()))
 }
 
 func (g *Generator) Generate() (string, error) {
-	if g.length <= 0 {
+	if g.payloadLength() <= 0 {
 		return "", ErrInvalidLength
 	}
 
+	for attempt := 0; attempt < maxFilterAttempts; attempt++ {
+		code, err := g.random()
+		if err != nil {
+			return "", err
+		}
+
+		if !g.blocked(code) {
+			return code, nil
+		}
+	}
+
+	return "", ErrBlockedCode
+}
+
+// random генерирует случайный код без фильтрации
+func (g *Generator) random() (string, error) {
 	var code strings.Builder
 	code.Grow(g.length)
 
-	charsetLength := big.NewInt(int64(len(base62Chars)))
+	chars := g.alphabet.chars
+	charsetLength := big.NewInt(int64(len(chars)))
 
-	for i := 0; i < g.length; i++ {
+	for i := 0; i < g.payloadLength(); i++ {
 		// Генерируем криптографически стойкое случайное число
 		randomIndex, err := rand.Int(rand.Reader, charsetLength)
 		if err != nil {
 			return "", err
 		}
 
-		code.WriteByte(base62Chars[randomIndex.Int64()])
+		code.WriteByte(chars[randomIndex.Int64()])
+	}
+
+	if g.checksum {
+		code.WriteByte(g.checkChar(code.String()))
 	}
 
 	return code.String(), nil
 }
 
-// EncodeID конвертирует числовой ID в Base62 строку
+// payloadLength количество случайных символов в коде
+func (g *Generator) payloadLength() int {
+	if g.checksum {
+		return g.length - 1
+	}
+	return g.length
+}
+
+// EncodeID конвертирует числовой ID в строку в алфавите генератора
 // Полезно для использования с автоинкрементом из БД
 func (g *Generator) EncodeID(id int64) string {
+	chars := g.alphabet.chars
 	if id == 0 {
-		return string(base62Chars[0])
+		return string(chars[0])
 	}
 
 	var result strings.Builder
-	base := int64(len(base62Chars))
+	base := int64(len(chars))
 
 	for id > 0 {
 		remainder := id % base
-		result.WriteByte(base62Chars[remainder])
+		result.WriteByte(chars[remainder])
 		id = id / base
 	}
 
@@ -72,16 +158,17 @@ func (g *Generator) EncodeID(id int64) string {
 	return reverse(result.String())
 }
 
-// DecodeID конвертирует Base62 строку обратно в числовой ID
+// DecodeID конвертирует строку в алфавите генератора обратно в числовой ID
 func (g *Generator) DecodeID(code string) (int64, error) {
 	var id int64
-	base := int64(len(base62Chars))
+	chars := g.alphabet.chars
+	base := int64(len(chars))
 
-	for _, char := range code {
+	for _, char := range g.alphabet.Normalize(code) {
 		// Находим позицию символа в алфавите
-		pos := strings.IndexRune(base62Chars, char)
+		pos := strings.IndexRune(chars, char)
 		if pos == -1 {
-			return 0, fmt.Errorf("ERROR")
+			return 0, fmt.Errorf("%w: unexpected character %q", ErrInvalidCode, char)
 		}
 
 		id = id*base + int64(pos)
@@ -96,8 +183,8 @@ func (g *Generator) ValidateCode(code string) bool {
 		return false
 	}
 
-	for _, char := range code {
-		if !strings.ContainsRune(base62Chars, char) {
+	for _, char := range g.alphabet.Normalize(code) {
+		if !strings.ContainsRune(g.alphabet.chars, char) {
 			return false
 		}
 	}
@@ -105,6 +192,16 @@ func (g *Generator) ValidateCode(code string) bool {
 	return true
 }
 
+// blocked сообщает, отвергает ли код какой-либо фильтр
+func (g *Generator) blocked(code string) bool {
+	for _, filter := range g.filters {
+		if filter.Blocked(code) {
+			return true
+		}
+	}
+	return false
+}
+
 // reverse переворачивает строку
 func reverse(s string) string {
 	runes := []rune(s)
@@ -0,0 +1,182 @@
+package generator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// feistelRounds количество раундов сети Фейстеля
+// Четыре раунда с криптографической функцией дают псевдослучайную перестановку
+const feistelRounds = 4
+
+// SequenceGenerator превращает последовательные ID из базы в короткие коды
+//
+// ID пропускается через ключевую перестановку (сеть Фейстеля над пространством
+// кодов заданной длины), затем кодируется EncodeID. Перестановка биективна,
+// поэтому разные ID дают разные коды без проверок в базе, а без ключа
+// соседние коды невозможно перечислить
+type SequenceGenerator struct {
+	gen      *Generator
+	key      []byte
+	domain   uint64 // количество кодов длины length
+	halfBits uint
+	mask     uint64
+}
+
+// NewSequenceGenerator создает генератор кодов фиксированной длины из ID
+func NewSequenceGenerator(length int, key []byte, opts ...Option) (*SequenceGenerator, error) {
+	if length <= 0 {
+		length = defaultLength
+	}
+
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+
+	gen := NewGenerator(length, opts...)
+	if gen.payloadLength() <= 0 {
+		return nil, ErrInvalidLength
+	}
+
+	domain, ok := pow(uint64(len(gen.alphabet.chars)), gen.payloadLength())
+	if !ok {
+		return nil, ErrInvalidLength
+	}
+
+	// Наименьшее четное число бит, покрывающее домен
+	totalBits := uint(bits.Len64(domain - 1))
+	if totalBits%2 == 1 {
+		totalBits++
+	}
+	halfBits := totalBits / 2
+
+	return &SequenceGenerator{
+		gen:      gen,
+		key:      key,
+		domain:   domain,
+		halfBits: halfBits,
+		mask:     1<<halfBits - 1,
+	}, nil
+}
+
+// Capacity возвращает количество ID, которые можно закодировать
+func (g *SequenceGenerator) Capacity() uint64 {
+	return g.domain
+}
+
+// Code возвращает короткий код для ID
+// ErrBlockedCode означает, что код для этого ID отвергнут фильтром — берите следующий ID
+func (g *SequenceGenerator) Code(id int64) (string, error) {
+	if id < 0 || uint64(id) >= g.domain {
+		return "", ErrIDOutOfRange
+	}
+
+	permuted := g.permute(uint64(id))
+	code := g.gen.EncodeID(int64(permuted))
+
+	// Дополняем нулевым символом алфавита до фиксированной длины
+	if pad := g.gen.payloadLength() - len(code); pad > 0 {
+		code = strings.Repeat(string(g.gen.alphabet.chars[0]), pad) + code
+	}
+
+	if g.gen.checksum {
+		code += string(g.gen.checkChar(code))
+	}
+
+	if g.gen.blocked(code) {
+		return "", ErrBlockedCode
+	}
+
+	return code, nil
+}
+
+// ID восстанавливает ID по коду, выданному Code
+func (g *SequenceGenerator) ID(code string) (int64, error) {
+	if len(code) != g.gen.length {
+		return 0, ErrInvalidCode
+	}
+
+	if g.gen.checksum {
+		if !g.gen.VerifyChecksum(code) {
+			return 0, ErrInvalidCode
+		}
+		code = code[:len(code)-1]
+	}
+
+	permuted, err := g.gen.DecodeID(code)
+	if err != nil {
+		return 0, err
+	}
+
+	if permuted < 0 || uint64(permuted) >= g.domain {
+		return 0, ErrInvalidCode
+	}
+
+	return int64(g.unpermute(uint64(permuted))), nil
+}
+
+// permute применяет сеть Фейстеля с cycle walking: результат вне домена
+// шифруется повторно, пока не попадет в домен — это сохраняет биективность
+func (g *SequenceGenerator) permute(x uint64) uint64 {
+	for {
+		x = g.encrypt(x)
+		if x < g.domain {
+			return x
+		}
+	}
+}
+
+// unpermute обратна permute
+func (g *SequenceGenerator) unpermute(x uint64) uint64 {
+	for {
+		x = g.decrypt(x)
+		if x < g.domain {
+			return x
+		}
+	}
+}
+
+func (g *SequenceGenerator) encrypt(x uint64) uint64 {
+	left, right := x>>g.halfBits, x&g.mask
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^g.round(round, right)
+	}
+	return left<<g.halfBits | right
+}
+
+func (g *SequenceGenerator) decrypt(x uint64) uint64 {
+	left, right := x>>g.halfBits, x&g.mask
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^g.round(round, left), left
+	}
+	return left<<g.halfBits | right
+}
+
+// round раундовая функция: HMAC-SHA256 от номера раунда и половины блока
+func (g *SequenceGenerator) round(round int, half uint64) uint64 {
+	var buf [9]byte
+	buf[0] = byte(round)
+	binary.BigEndian.PutUint64(buf[1:], half)
+
+	mac := hmac.New(sha256.New, g.key)
+	mac.Write(buf[:])
+
+	return binary.BigEndian.Uint64(mac.Sum(nil)) & g.mask
+}
+
+// pow возвращает base^exp или false при переполнении int64
+func pow(base uint64, exp int) (uint64, bool) {
+	result := uint64(1)
+	for i := 0; i < exp; i++ {
+		hi, lo := bits.Mul64(result, base)
+		if hi != 0 || lo > math.MaxInt64 {
+			return 0, false
+		}
+		result = lo
+	}
+	return result, true
+}
@@ -0,0 +1,43 @@
+package generator
+
+import "testing"
+
+func TestSequenceGenerator(t *testing.T) {
+	g, err := NewSequenceGenerator(2, []byte("secret"))
+	if err != nil {
+		t.Fatalf("NewSequenceGenerator failed: %v", err)
+	}
+
+	// Перестановка биективна на всем домене: коды уникальны и обратимы
+	seen := make(map[string]bool, g.Capacity())
+	for id := int64(0); id < int64(g.Capacity()); id++ {
+		code, err := g.Code(id)
+		if err != nil {
+			t.Fatalf("Code(%d) failed: %v", id, err)
+		}
+		if len(code) != 2 {
+			t.Fatalf("Expected code length 2, got %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("Duplicate code %q for id %d", code, id)
+		}
+		seen[code] = true
+
+		back, err := g.ID(code)
+		if err != nil || back != id {
+			t.Fatalf("ID(%q) = %d, %v; want %d", code, back, err, id)
+		}
+	}
+
+	if _, err := g.Code(int64(g.Capacity())); err != ErrIDOutOfRange {
+		t.Errorf("Expected ErrIDOutOfRange, got %v", err)
+	}
+
+	// Другой ключ — другая перестановка
+	other, _ := NewSequenceGenerator(2, []byte("another"))
+	a, _ := g.Code(1)
+	b, _ := other.Code(1)
+	if a == b {
+		t.Errorf("Expected different codes for different keys, got %q", a)
+	}
+}
@@ -0,0 +1,150 @@
+package generator
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	// maxWordCount больше трех слов почти никогда не укладываются в maxWordCodeLength
+	maxWordCount = 3
+
+	// maxWordCodeLength ограничение длины кода (колонка short_code VARCHAR(20))
+	maxWordCodeLength = 20
+
+	// wordNumberMin, wordNumberMax диапазон числового суффикса: всегда две цифры
+	wordNumberMin = 10
+	wordNumberMax = 99
+)
+
+var (
+	//go:embed words/adjectives.txt
+	adjectivesFile string
+
+	//go:embed words/nouns.txt
+	nounsFile string
+
+	adjectives = parseWordList(adjectivesFile)
+	nouns      = parseWordList(nounsFile)
+)
+
+// WordGenerator генерирует читаемые коды вида brave-otter-42:
+// words-1 прилагательных, существительное и двузначное число через разделитель
+//
+// Такие коды легко продиктовать и напечатать; символы совпадают с теми,
+// что разрешены в кастомных кодах
+type WordGenerator struct {
+	words     int
+	separator string
+	filters   []Filter
+}
+
+// NewWordGenerator создает генератор кодов из words слов (1–3) с разделителем "-" или "_"
+// Из opts учитываются только фильтры — алфавит к словам не применяется
+func NewWordGenerator(words int, separator string, opts ...Option) (*WordGenerator, error) {
+	if words < 1 || words > maxWordCount {
+		return nil, fmt.Errorf("%w: word count must be between 1 and %d", ErrInvalidLength, maxWordCount)
+	}
+
+	if separator != "-" && separator != "_" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSeparator, separator)
+	}
+
+	g := NewGenerator(defaultLength, opts...)
+
+	return &WordGenerator{
+		words:     words,
+		separator: separator,
+		filters:   g.filters,
+	}, nil
+}
+
+// Generate возвращает случайный словесный код
+// Коды длиннее maxWordCodeLength и отвергнутые фильтрами перегенерируются
+func (g *WordGenerator) Generate() (string, error) {
+	for attempt := 0; attempt < maxFilterAttempts; attempt++ {
+		code, err := g.random()
+		if err != nil {
+			return "", err
+		}
+
+		if len(code) > maxWordCodeLength || g.blocked(code) {
+			continue
+		}
+
+		return code, nil
+	}
+
+	return "", ErrBlockedCode
+}
+
+// Capacity возвращает количество различных кодов (без учета отбраковки по длине)
+func (g *WordGenerator) Capacity() float64 {
+	adjectiveCombinations := math.Pow(float64(len(adjectives)), float64(g.words-1))
+	return adjectiveCombinations * float64(len(nouns)) * (wordNumberMax - wordNumberMin + 1)
+}
+
+func (g *WordGenerator) random() (string, error) {
+	parts := make([]string, 0, g.words+1)
+
+	for i := 0; i < g.words-1; i++ {
+		word, err := pick(adjectives)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, word)
+	}
+
+	noun, err := pick(nouns)
+	if err != nil {
+		return "", err
+	}
+	parts = append(parts, noun)
+
+	number, err := rand.Int(rand.Reader, big.NewInt(wordNumberMax-wordNumberMin+1))
+	if err != nil {
+		return "", err
+	}
+	parts = append(parts, fmt.Sprint(wordNumberMin+number.Int64()))
+
+	return strings.Join(parts, g.separator), nil
+}
+
+func (g *WordGenerator) blocked(code string) bool {
+	for _, filter := range g.filters {
+		if filter.Blocked(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// pick возвращает случайное слово из списка
+func pick(words []string) (string, error) {
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(len(words))))
+	if err != nil {
+		return "", err
+	}
+	return words[index.Int64()], nil
+}
+
+// parseWordList разбирает встроенный список: слово на строку, # — комментарий
+func parseWordList(data string) []string {
+	var words []string
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	return words
+}
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+type prefixFilter string
+
+func (p prefixFilter) Blocked(code string) bool {
+	return len(code) >= len(p) && code[:len(p)] == string(p)
+}
+
+func TestWordGenerator(t *testing.T) {
+	tests := []struct {
+		words     int
+		separator string
+		pattern   string
+	}{
+		{1, "-", `^[a-z]{3,6}-\d{2}$`},
+		{2, "-", `^[a-z]{3,6}-[a-z]{3,6}-\d{2}$`},
+		{3, "_", `^[a-z]{3,6}_[a-z]{3,6}_[a-z]{3,6}_\d{2}$`},
+	}
+
+	for _, tt := range tests {
+		g, err := NewWordGenerator(tt.words, tt.separator)
+		if err != nil {
+			t.Fatalf("NewWordGenerator(%d, %q) failed: %v", tt.words, tt.separator, err)
+		}
+
+		pattern := regexp.MustCompile(tt.pattern)
+		for i := 0; i < 100; i++ {
+			code, err := g.Generate()
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if !pattern.MatchString(code) || len(code) > maxWordCodeLength {
+				t.Fatalf("Code %q does not match %s", code, tt.pattern)
+			}
+		}
+	}
+}
+
+func TestWordGenerator_Filter(t *testing.T) {
+	g, err := NewWordGenerator(1, "-", WithFilter(prefixFilter("a")))
+	if err != nil {
+		t.Fatalf("NewWordGenerator failed: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		code, err := g.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if code[0] == 'a' {
+			t.Fatalf("Filtered code %q was generated", code)
+		}
+	}
+}
+
+func TestNewWordGenerator_Invalid(t *testing.T) {
+	if _, err := NewWordGenerator(0, "-"); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("Expected ErrInvalidLength, got %v", err)
+	}
+	if _, err := NewWordGenerator(2, "."); !errors.Is(err, ErrInvalidSeparator) {
+		t.Errorf("Expected ErrInvalidSeparator, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package threatlist
+
+import (
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Ограничения на число выражений, как в Safe Browsing
+const (
+	maxHostSuffixes = 5 // точный хост и до 4 суффиксов
+	maxPathPrefixes = 4 // корень и до 3 вложенных директорий
+)
+
+// canonical канонический вид URL для построения выражений
+type canonical struct {
+	host  string
+	path  string
+	query string
+}
+
+// Expressions возвращает выражения host/path, которые проверяются по списку:
+// для http://a.b.c/1/2.html?p=1 это a.b.c/1/2.html?p=1, a.b.c/1/2.html,
+// a.b.c/, a.b.c/1/, b.c/1/2.html?p=1, ... Порядок — от точного к общему
+func Expressions(rawURL string) ([]string, error) {
+	c, err := canonicalize(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := hostSuffixes(c.host)
+	paths := pathPrefixes(c.path, c.query)
+
+	expressions := make([]string, 0, len(hosts)*len(paths))
+	for _, host := range hosts {
+		for _, p := range paths {
+			expressions = append(expressions, host+p)
+		}
+	}
+
+	return expressions, nil
+}
+
+// canonicalize приводит URL к виду Safe Browsing: без схемы, порта и фрагмента,
+// хост в нижнем регистре без крайних точек, путь без . и .. сегментов
+func canonicalize(rawURL string) (canonical, error) {
+	raw := strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(rawURL))
+
+	raw, _, _ = strings.Cut(raw, "#")
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return canonical{}, ErrInvalidURL
+	}
+
+	host := strings.Trim(strings.ToLower(u.Hostname()), ".")
+	for strings.Contains(host, "..") {
+		host = strings.ReplaceAll(host, "..", ".")
+	}
+	if host == "" {
+		return canonical{}, ErrInvalidURL
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		host = addr.Unmap().String()
+	}
+
+	return canonical{
+		host:  host,
+		path:  canonicalPath(u.Path),
+		query: u.RawQuery,
+	}, nil
+}
+
+// canonicalPath убирает . и .., схлопывает // и экранирует управляющие символы,
+// пробелы, #, % и не-ASCII — так же, как при подготовке записей списка
+func canonicalPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(cleaned); i++ {
+		c := cleaned[i]
+		if c <= 0x20 || c >= 0x7f || c == '#' || c == '%' {
+			b.WriteByte('%')
+			b.WriteByte("0123456789ABCDEF"[c>>4])
+			b.WriteByte("0123456789ABCDEF"[c&0x0f])
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// hostSuffixes возвращает точный хост и суффиксы из последних пяти меток
+// без домена верхнего уровня; для IP — только сам адрес
+func hostSuffixes(host string) []string {
+	suffixes := []string{host}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return suffixes
+	}
+
+	labels := strings.Split(host, ".")
+	start := max(len(labels)-maxHostSuffixes, 1)
+	for i := start; i <= len(labels)-2; i++ {
+		suffixes = append(suffixes, strings.Join(labels[i:], "."))
+	}
+
+	return suffixes
+}
+
+// pathPrefixes возвращает путь с query, путь без query, корень
+// и до трех вложенных директорий
+func pathPrefixes(p, query string) []string {
+	var prefixes []string
+	seen := make(map[string]struct{})
+	add := func(prefix string) {
+		if _, dup := seen[prefix]; !dup {
+			seen[prefix] = struct{}{}
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	if query != "" {
+		add(p + "?" + query)
+	}
+	add(p)
+
+	add("/")
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	current := "/"
+	for i := 0; i < len(segments)-1 && i < maxPathPrefixes-1; i++ {
+		current += segments[i] + "/"
+		add(current)
+	}
+
+	return prefixes
+}
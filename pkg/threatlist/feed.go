@@ -0,0 +1,106 @@
+package threatlist
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Feed списки из файлов, которые можно перечитать без перезапуска
+// При ошибке чтения остается действовать прежний список
+type Feed struct {
+	paths []string
+	list  atomic.Pointer[List]
+
+	mu       sync.Mutex // сериализует перечитывание
+	versions []fileVersion
+}
+
+// fileVersion время модификации и размер файла на момент загрузки
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// Open загружает списки из файлов
+func Open(paths ...string) (*Feed, error) {
+	f := &Feed{paths: paths}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Len возвращает количество записей в действующем списке
+func (f *Feed) Len() int {
+	return f.list.Load().Len()
+}
+
+// Check ищет URL в действующем списке
+func (f *Feed) Check(rawURL string) (Match, bool) {
+	return f.list.Load().Check(rawURL)
+}
+
+// Reload перечитывает все файлы (например, по SIGHUP)
+func (f *Feed) Reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	versions, err := f.stat()
+	if err != nil {
+		return err
+	}
+
+	return f.load(versions)
+}
+
+// ReloadIfChanged перечитывает файлы, если хотя бы один из них изменился
+func (f *Feed) ReloadIfChanged() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	versions, err := f.stat()
+	if err != nil {
+		return false, err
+	}
+
+	changed := len(versions) != len(f.versions)
+	for i := 0; !changed && i < len(versions); i++ {
+		changed = !versions[i].modTime.Equal(f.versions[i].modTime) || versions[i].size != f.versions[i].size
+	}
+	if !changed {
+		return false, nil
+	}
+
+	if err := f.load(versions); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (f *Feed) stat() ([]fileVersion, error) {
+	versions := make([]fileVersion, len(f.paths))
+	for i, path := range f.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open threat list: %w", err)
+		}
+		versions[i] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+	}
+	return versions, nil
+}
+
+func (f *Feed) load(versions []fileVersion) error {
+	// Версии запоминаются и при ошибке, чтобы не повторять ее на каждой проверке
+	f.versions = versions
+
+	list, err := Load(f.paths...)
+	if err != nil {
+		return err
+	}
+
+	f.list.Store(list)
+	return nil
+}
@@ -0,0 +1,202 @@
+// Package threatlist проверяет URL по локальным спискам вредоносных адресов
+// в стиле Safe Browsing
+//
+// URL канонизируется и раскладывается на выражения host/path: суффиксы хоста
+// и префиксы пути (evil.example/, evil.example/login/, ...). SHA-256 каждого
+// выражения сравнивается с префиксами хешей из списков. Совпадение префикса
+// считается попаданием: полных хешей для уточнения локально нет
+package threatlist
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultThreatType тип угрозы для записей без явного типа
+const DefaultThreatType = "malicious"
+
+// Границы длины префикса хеша в байтах
+const (
+	minPrefixLength = 4
+	maxPrefixLength = sha256.Size
+)
+
+var (
+	ErrInvalidEntry = errors.New("invalid threat list entry")
+	ErrInvalidURL   = errors.New("invalid URL")
+)
+
+// Match описывает попадание URL в список
+type Match struct {
+	ThreatType string // phishing, malware, ...
+	Expression string // выражение host/path, совпавшее со списком
+}
+
+// List набор префиксов хешей вредоносных выражений
+type List struct {
+	prefixes map[int]map[string]string // длина префикса → префикс → тип угрозы
+	lengths  []int                     // длины префиксов по убыванию
+	entries  int
+}
+
+// New создает пустой список
+func New() *List {
+	return &List{prefixes: make(map[int]map[string]string)}
+}
+
+// Parse читает список: одна запись на строку, # — комментарий
+//
+//	<запись> [тип угрозы]
+//
+// Запись — hex-префикс SHA-256 выражения (от 8 до 64 символов) или URL-выражение
+// вида evil.example/login, которое канонизируется и хешируется при загрузке
+func Parse(r io.Reader) (*List, error) {
+	l := New()
+	if err := l.read(r); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Load читает и объединяет списки из файлов
+func Load(paths ...string) (*List, error) {
+	l := New()
+
+	for _, path := range paths {
+		if err := l.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+func (l *List) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open threat list: %w", err)
+	}
+	defer file.Close()
+
+	if err := l.read(file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (l *List) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return fmt.Errorf("%w on line %d: expected \"<entry> [threat type]\"", ErrInvalidEntry, n)
+		}
+
+		threatType := DefaultThreatType
+		if len(fields) == 2 {
+			threatType = strings.ToLower(fields[1])
+		}
+
+		if err := l.Add(fields[0], threatType); err != nil {
+			return fmt.Errorf("%w on line %d", err, n)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read threat list: %w", err)
+	}
+
+	return nil
+}
+
+// Add добавляет hex-префикс хеша или URL-выражение
+func (l *List) Add(entry, threatType string) error {
+	prefix, err := parseEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	set, ok := l.prefixes[len(prefix)]
+	if !ok {
+		set = make(map[string]string)
+		l.prefixes[len(prefix)] = set
+
+		l.lengths = append(l.lengths, len(prefix))
+		sort.Sort(sort.Reverse(sort.IntSlice(l.lengths)))
+	}
+
+	if _, dup := set[string(prefix)]; !dup {
+		l.entries++
+	}
+	set[string(prefix)] = threatType
+
+	return nil
+}
+
+// parseEntry возвращает префикс хеша записи
+// В выражениях всегда есть точка или слэш — в hex-префиксах их нет
+func parseEntry(entry string) ([]byte, error) {
+	if !strings.ContainsAny(entry, "./") {
+		prefix, err := hex.DecodeString(entry)
+		if err != nil || len(prefix) < minPrefixLength || len(prefix) > maxPrefixLength {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidEntry, entry)
+		}
+		return prefix, nil
+	}
+
+	c, err := canonicalize(entry)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidEntry, entry)
+	}
+
+	expression := c.host + c.path
+	if c.query != "" {
+		expression += "?" + c.query
+	}
+
+	hash := sha256.Sum256([]byte(expression))
+	return hash[:], nil
+}
+
+// Len возвращает количество записей
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return l.entries
+}
+
+// Check ищет URL в списке; невалидный URL не считается попаданием
+func (l *List) Check(rawURL string) (Match, bool) {
+	if l == nil || l.entries == 0 {
+		return Match{}, false
+	}
+
+	expressions, err := Expressions(rawURL)
+	if err != nil {
+		return Match{}, false
+	}
+
+	for _, expression := range expressions {
+		hash := sha256.Sum256([]byte(expression))
+
+		for _, length := range l.lengths {
+			if threatType, ok := l.prefixes[length][string(hash[:length])]; ok {
+				return Match{ThreatType: threatType, Expression: expression}, true
+			}
+		}
+	}
+
+	return Match{}, false
+}
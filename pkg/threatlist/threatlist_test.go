@@ -0,0 +1,134 @@
+package threatlist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpressions(t *testing.T) {
+	tests := map[string][]string{
+		"http://a.b.c/1/2.html?param=1": {
+			"a.b.c/1/2.html?param=1", "a.b.c/1/2.html", "a.b.c/", "a.b.c/1/",
+			"b.c/1/2.html?param=1", "b.c/1/2.html", "b.c/", "b.c/1/",
+		},
+		"https://A.B.C.D.E.F.G:8443/1.html#frag": {
+			"a.b.c.d.e.f.g/1.html", "a.b.c.d.e.f.g/",
+			"c.d.e.f.g/1.html", "c.d.e.f.g/",
+			"d.e.f.g/1.html", "d.e.f.g/",
+			"e.f.g/1.html", "e.f.g/",
+			"f.g/1.html", "f.g/",
+		},
+		"http://1.2.3.4/a/./b/../c/": {"1.2.3.4/a/c/", "1.2.3.4/", "1.2.3.4/a/"},
+		"evil.example..":             {"evil.example/"},
+	}
+
+	for input, want := range tests {
+		got, err := Expressions(input)
+		if err != nil {
+			t.Errorf("Expressions(%q) failed: %v", input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expressions(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestList_Check(t *testing.T) {
+	hash := sha256.Sum256([]byte("login.bank.example/secure/"))
+
+	list, err := Parse(strings.NewReader(`
+# URL-выражения
+evil.example                phishing
+cdn.example/payload/        malware
+# Префикс хеша login.bank.example/secure/
+` + hex.EncodeToString(hash[:4]) + `
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if list.Len() != 3 {
+		t.Errorf("Expected 3 entries, got %d", list.Len())
+	}
+
+	tests := []struct {
+		url        string
+		threatType string
+	}{
+		{"https://evil.example/", "phishing"},
+		{"https://www.evil.example/any/path?q=1", "phishing"},
+		{"http://cdn.example/payload/x.exe", "malware"},
+		{"http://cdn.example/other/x.exe", ""},
+		{"https://login.bank.example/secure/form?id=1", DefaultThreatType},
+		{"https://bank.example/secure/", ""},
+		{"https://example.com/", ""},
+	}
+
+	for _, tt := range tests {
+		match, flagged := list.Check(tt.url)
+		if flagged != (tt.threatType != "") || match.ThreatType != tt.threatType {
+			t.Errorf("Check(%q) = %+v, %v; want threat type %q", tt.url, match, flagged, tt.threatType)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"abc",                   // короче 4 байт
+		"zzzzzzzzzz",            // не hex
+		"evil.example a b",      // лишнее поле
+		"http:///no-host/path/", // нет хоста
+	} {
+		if _, err := Parse(strings.NewReader(input)); !errors.Is(err, ErrInvalidEntry) {
+			t.Errorf("Parse(%q): expected ErrInvalidEntry, got %v", input, err)
+		}
+	}
+}
+
+func TestFeed_ReloadIfChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.txt")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Chtimes failed: %v", err)
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write("evil.example\n", start)
+
+	feed, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, flagged := feed.Check("https://new-threat.example/"); flagged {
+		t.Fatal("Expected URL not to be flagged before update")
+	}
+
+	// Ссылка становится опасной после обновления списка
+	write("evil.example\nnew-threat.example\n", start.Add(time.Minute))
+	if reloaded, err := feed.ReloadIfChanged(); !reloaded || err != nil {
+		t.Fatalf("Expected reload, got %v, %v", reloaded, err)
+	}
+	if _, flagged := feed.Check("https://new-threat.example/"); !flagged {
+		t.Error("Expected URL to be flagged after update")
+	}
+
+	// Ошибочный файл не заменяет действующий список
+	write("zz\n", start.Add(2*time.Minute))
+	if _, err := feed.ReloadIfChanged(); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("Expected ErrInvalidEntry, got %v", err)
+	}
+	if feed.Len() != 2 {
+		t.Errorf("Expected previous list with 2 entries, got %d", feed.Len())
+	}
+}